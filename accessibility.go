@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+const (
+	osmWheelchairType   = "wheelchair_accessible"
+	accessibilityLookup = 5
+)
+
+var fieldEditorialSummary = maps.PlaceDetailsFieldMask("editorial_summary")
+
+var accessibilityCache = newTTLCache(24 * time.Hour)
+
+// Accessibility is only attached when Google reports an accessible entrance.
+// The maps client decodes the field as a plain bool, so a place without one
+// and a place Google has no data for look the same and both get no
+// Accessibility.
+type Accessibility struct {
+	WheelchairAccessibleEntrance *bool `json:"wheelchairAccessibleEntrance"`
+}
+
+type PlaceDetail struct {
+	maps.PlaceDetailsResult
+	Accessibility *Accessibility     `json:"accessibility,omitempty"`
+	Hours         *WeeklySchedule    `json:"hours,omitempty"`
+	Phone         *PhoneNumber       `json:"phone,omitempty"`
+	Address       *StructuredAddress `json:"address,omitempty"`
+	ReviewFilters []ReviewFilter     `json:"reviewFilters,omitempty"`
+	Summary       *ReviewDigest      `json:"summary,omitempty"`
+}
+
+var detailFields = []maps.PlaceDetailsFieldMask{
+	maps.PlaceDetailsFieldMaskPlaceID,
+	maps.PlaceDetailsFieldMaskName,
+	maps.PlaceDetailsFieldMaskFormattedAddress,
+	maps.PlaceDetailsFieldMaskAddressComponent,
+	maps.PlaceDetailsFieldMaskGeometry,
+	maps.PlaceDetailsFieldMaskTypes,
+	maps.PlaceDetailsFieldMaskURL,
+	maps.PlaceDetailsFieldMaskWebsite,
+	maps.PlaceDetailsFieldMaskOpeningHours,
+	maps.PlaceDetailsFieldMaskUTCOffset,
+	maps.PlaceDetailsFieldMaskPhotos,
+	maps.PlaceDetailsFieldMaskPriceLevel,
+	maps.PlaceDetailsFieldMaskRatings,
+	maps.PlaceDetailsFieldMaskUserRatingsTotal,
+	maps.PlaceDetailsFieldMaskBusinessStatus,
+	maps.PlaceDetailsFieldMaskFormattedPhoneNumber,
+	maps.PlaceDetailsFieldMaskInternationalPhoneNumber,
+	maps.PlaceDetailsFieldMaskReviews,
+	fieldEditorialSummary,
+	maps.PlaceDetailsFieldMaskWheelchairAccessibleEntrance,
+}
+
+func handleDetails(parameters BiteBody) (events.APIGatewayProxyResponse, error) {
+	if parameters.PlaceID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	place, err := respondPlaceDetails(parameters.PlaceID, detailFields...)
+	if err != nil {
+		return detailsFailure(err)
+	}
+	detail := newPlaceDetail(place)
+	detail.Phone = normalizePhone(place.InternationalPhoneNumber, localeFor(parameters.AcceptLanguage))
+	filterReviews(&detail, parameters.Debug)
+	if parameters.IncludeSummary {
+		detail.Summary, err = reviewDigest(context.Background(), detail.PlaceDetailsResult)
+		if err != nil {
+			errorLogger.Println(err)
+		}
+	}
+	return jsonSuccess(detail)
+}
+
+func newPlaceDetail(place maps.PlaceDetailsResult) PlaceDetail {
+	for i := range place.Reviews {
+		place.Reviews[i].Text = sanitizeText(place.Reviews[i].Text)
+	}
+	if place.EditorialSummary != nil {
+		summary := *place.EditorialSummary
+		summary.Overview = sanitizeText(summary.Overview)
+		place.EditorialSummary = &summary
+	}
+	ctx := context.Background()
+	loc := placeTimezone(ctx, place)
+	now := time.Now()
+	detail := PlaceDetail{
+		PlaceDetailsResult: place,
+		Hours:              normalizeHours(place.OpeningHours, loc, now),
+		Address:            parseAddressComponents(place.AddressComponents),
+	}
+	applySpecialDays(ctx, detail.Hours, place.PlaceID, loc, now)
+	if place.WheelchairAccessibleEntrance {
+		detail.Accessibility = &Accessibility{WheelchairAccessibleEntrance: &place.WheelchairAccessibleEntrance}
+	}
+	return detail
+}
+
+// wheelchairAccessible reports whether a result is known to have an
+// accessible entrance. OSM results carry it as a type from their wheelchair
+// tag; everything else needs a details lookup, which is cached. Google's
+// answer can't tell "no" from "unknown", so both read as not accessible.
+func wheelchairAccessible(result maps.PlacesSearchResult) bool {
+	for _, t := range result.Types {
+		if t == osmWheelchairType {
+			return true
+		}
+	}
+	if strings.HasPrefix(result.PlaceID, foursquareIDPrefix) {
+		return false
+	}
+	if cached, ok := accessibilityCache.get(result.PlaceID); ok {
+		return cached.(bool)
+	}
+	place, err := respondPlaceDetails(result.PlaceID, maps.PlaceDetailsFieldMaskWheelchairAccessibleEntrance)
+	if err != nil {
+		logDetailsError(err)
+		return false
+	}
+	accessible := place.WheelchairAccessibleEntrance
+	accessibilityCache.set(result.PlaceID, accessible)
+	return accessible
+}
+
+// filterAccessible drops results without a confirmed wheelchair-accessible
+// entrance. Unknown counts as not accessible, since a wrong yes is worse for
+// the user than a missing result.
+func filterAccessible(ctx context.Context, biteArray *BiteResponse) {
+	keep := make([]bool, len(biteArray.Results))
+	sem := make(chan struct{}, accessibilityLookup)
+	var wg sync.WaitGroup
+	for i, result := range biteArray.Results {
+		wg.Add(1)
+		go func(i int, result maps.PlacesSearchResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() == nil {
+				keep[i] = wheelchairAccessible(result)
+			}
+		}(i, result.PlacesSearchResult)
+	}
+	wg.Wait()
+	kept := biteArray.Results[:0]
+	for i, result := range biteArray.Results {
+		if keep[i] {
+			kept = append(kept, result)
+		}
+	}
+	biteArray.Results = kept
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const addressConcurrency = 5
+
+var addressCache = newTTLCache(7 * 24 * time.Hour)
+
+// numberAfterRoute lists countries that write the house number after the
+// street name ("Hauptstraße 5").
+var numberAfterRoute = map[string]bool{
+	"DE": true, "AT": true, "CH": true, "NL": true, "BE": true, "DK": true, "SE": true, "NO": true,
+	"FI": true, "PL": true, "CZ": true, "ES": true, "IT": true, "PT": true, "MX": true, "AR": true, "BR": true,
+}
+
+type StructuredAddress struct {
+	Street       string `json:"street,omitempty"`
+	StreetNumber string `json:"streetNumber,omitempty"`
+	Route        string `json:"route,omitempty"`
+	City         string `json:"city,omitempty"`
+	Region       string `json:"region,omitempty"`
+	RegionCode   string `json:"regionCode,omitempty"`
+	PostalCode   string `json:"postalCode,omitempty"`
+	Country      string `json:"country,omitempty"`
+	CountryCode  string `json:"countryCode,omitempty"`
+}
+
+// parseAddressComponents maps Google's address_components onto fixed fields.
+// City falls back through the component types different countries use for
+// it.
+func parseAddressComponents(components []maps.AddressComponent) *StructuredAddress {
+	if len(components) == 0 {
+		return nil
+	}
+	byType := map[string]maps.AddressComponent{}
+	for _, c := range components {
+		for _, t := range c.Types {
+			if _, ok := byType[t]; !ok {
+				byType[t] = c
+			}
+		}
+	}
+	a := &StructuredAddress{
+		StreetNumber: byType["street_number"].LongName,
+		Route:        byType["route"].LongName,
+		Region:       byType["administrative_area_level_1"].LongName,
+		RegionCode:   byType["administrative_area_level_1"].ShortName,
+		PostalCode:   byType["postal_code"].LongName,
+		Country:      byType["country"].LongName,
+		CountryCode:  byType["country"].ShortName,
+	}
+	for _, t := range []string{"locality", "postal_town", "sublocality_level_1", "administrative_area_level_2"} {
+		if c, ok := byType[t]; ok {
+			a.City = c.LongName
+			break
+		}
+	}
+	parts := []string{a.StreetNumber, a.Route}
+	if numberAfterRoute[a.CountryCode] {
+		parts = []string{a.Route, a.StreetNumber}
+	}
+	a.Street = strings.TrimSpace(strings.Join(parts, " "))
+	return a
+}
+
+// placeAddress looks up and caches a place's structured address.
+func placeAddress(placeID string) (*StructuredAddress, error) {
+	if cached, ok := addressCache.get(placeID); ok {
+		return cached.(*StructuredAddress), nil
+	}
+	place, err := respondPlaceDetails(placeID, maps.PlaceDetailsFieldMaskAddressComponent)
+	if err != nil {
+		return nil, err
+	}
+	address := parseAddressComponents(place.AddressComponents)
+	addressCache.set(placeID, address)
+	return address, nil
+}
+
+// resultAddresses resolves structured addresses for a page of results, in
+// result order. Lookups that fail leave a nil entry.
+func resultAddresses(results []BiteResult) []*StructuredAddress {
+	addresses := make([]*StructuredAddress, len(results))
+	sem := make(chan struct{}, addressConcurrency)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			address, err := placeAddress(results[i].PlaceID)
+			if err != nil {
+				logDetailsError(err)
+				return
+			}
+			addresses[i] = address
+		}(i)
+	}
+	wg.Wait()
+	return addresses
+}
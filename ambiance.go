@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	ambianceQuiet     = "quiet"
+	ambianceLively    = "lively"
+	ambianceGroups    = "good-for-groups"
+	ambianceDateNight = "date-night"
+
+	ambianceConcurrency = 5
+	// ambianceMinMentions is how many reviews must mention a tag before it is
+	// applied, so one reviewer's bad night doesn't label a place.
+	ambianceMinMentions = 2
+)
+
+var ambianceCache = newTTLCache(24 * time.Hour)
+
+type ambianceTag struct {
+	ID      string
+	Label   string
+	Phrases []string
+	// Opposite is a tag whose mentions count against this one.
+	Opposite string
+}
+
+var ambianceTags = []ambianceTag{
+	{ID: ambianceQuiet, Label: "Quiet", Phrases: []string{"quiet", "peaceful", "calm", "relaxed", "could hear each other", "easy to talk", "low-key"}, Opposite: ambianceLively},
+	{ID: ambianceLively, Label: "Lively", Phrases: []string{"lively", "loud", "noisy", "buzzing", "vibrant", "energetic", "packed", "great vibe"}, Opposite: ambianceQuiet},
+	{ID: ambianceGroups, Label: "Good for groups", Phrases: []string{"large group", "big group", "our group", "group of", "party of", "birthday", "long tables", "family style"}},
+	{ID: ambianceDateNight, Label: "Date night", Phrases: []string{"date night", "romantic", "anniversary", "intimate", "candlelit", "first date", "cozy"}},
+}
+
+func validAmbiance(tags []string) bool {
+	for _, tag := range tags {
+		if !knownAmbiance(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func knownAmbiance(tag string) bool {
+	for _, t := range ambianceTags {
+		if t.ID == tag {
+			return true
+		}
+	}
+	return false
+}
+
+var ambianceError = &BodyError{Error: fmt.Sprintf("must be one of %s", strings.Join(ambianceTagIDs(), ", ")), Field: "ambiance"}
+
+func ambianceTagIDs() []string {
+	ids := make([]string, len(ambianceTags))
+	for i, t := range ambianceTags {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// mineAmbiance counts the reviews mentioning each tag's phrases and keeps
+// tags with enough mentions that outnumber their opposite's.
+func mineAmbiance(reviews []maps.PlaceReview) []string {
+	mentions := map[string]int{}
+	for _, review := range reviews {
+		text := strings.ToLower(review.Text)
+		for _, t := range ambianceTags {
+			if containsAny(text, t.Phrases) {
+				mentions[t.ID]++
+			}
+		}
+	}
+	var tags []string
+	for _, t := range ambianceTags {
+		n := mentions[t.ID]
+		if n >= ambianceMinMentions && n > mentions[t.Opposite] {
+			tags = append(tags, t.ID)
+		}
+	}
+	return tags
+}
+
+// placeAmbiance merges tags mined from Google reviews with ambiance tags the
+// community has submitted. Other providers' places only have the latter, as
+// does a place whose reviews failed to load; that result isn't cached, so the
+// reviews are retried on the next request.
+func placeAmbiance(ctx context.Context, placeID string) []string {
+	if cached, ok := ambianceCache.get(placeID); ok {
+		return cached.([]string)
+	}
+	var tags []string
+	reviews, err := placeReviews(placeID)
+	if err != nil {
+		logDetailsError(err)
+	} else {
+		tags = mineAmbiance(reviews)
+	}
+	for _, tag := range placeTags(ctx, placeID) {
+		if knownAmbiance(tag) && !contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if err == nil {
+		ambianceCache.set(placeID, tags)
+	}
+	return tags
+}
+
+// enrichAmbiance attaches ambiance tags to every result. Each uncached
+// result costs a details call for its reviews.
+func enrichAmbiance(ctx context.Context, biteArray *BiteResponse) {
+	sem := make(chan struct{}, ambianceConcurrency)
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		wg.Add(1)
+		go func(result *BiteResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() == nil {
+				result.Ambiance = placeAmbiance(ctx, result.PlaceID)
+			}
+		}(&biteArray.Results[i])
+	}
+	wg.Wait()
+}
+
+// filterAmbiance keeps results carrying every wanted tag.
+func filterAmbiance(biteArray *BiteResponse, wanted []string) {
+	kept := make([]BiteResult, 0, len(biteArray.Results))
+	for _, result := range biteArray.Results {
+		if hasAll(result.Ambiance, wanted) {
+			kept = append(kept, result)
+		}
+	}
+	biteArray.Results = kept
+}
+
+func hasAll(have, wanted []string) bool {
+	for _, w := range wanted {
+		if !contains(have, w) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	placesNewURL          = "https://places.googleapis.com/v1/places/"
+	attributeFieldMask    = "goodForChildren,allowsDogs,servesBeer,servesWine,servesCocktails,primaryType"
+	attributesConcurrency = 5
+)
+
+var attributesCache = newTTLCache(24 * time.Hour)
+
+// PlaceAttributes are amenities only Places API (New) returns; the legacy
+// Place Details the maps client speaks doesn't have them. Nil means unknown.
+type PlaceAttributes struct {
+	GoodForChildren *bool `json:"goodForChildren,omitempty"`
+	AllowsDogs      *bool `json:"allowsDogs,omitempty"`
+	ServesBeer      *bool `json:"servesBeer,omitempty"`
+	ServesWine      *bool `json:"servesWine,omitempty"`
+	ServesCocktails *bool `json:"servesCocktails,omitempty"`
+
+	PrimaryType string `json:"primaryType,omitempty"`
+}
+
+// barTypes are primary types for venues that are mainly about drinking and
+// often 21+.
+var barTypes = []string{"bar", "night_club", "pub", "wine_bar", "cocktail_bar"}
+
+func fetchPlaceAttributes(ctx context.Context, placeID string) (*PlaceAttributes, error) {
+	if strings.HasPrefix(placeID, osmIDPrefix) {
+		return nil, &detailsUnsupportedError{placeID: placeID}
+	}
+	if cached, ok := attributesCache.get(placeID); ok {
+		return cached.(*PlaceAttributes), nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, placesNewURL+url.PathEscape(placeID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Goog-Api-Key", cfg.GoogleAPIKey)
+	req.Header.Set("X-Goog-FieldMask", attributeFieldMask)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("places: %s", resp.Status)
+	}
+	attributes := &PlaceAttributes{}
+	err = json.NewDecoder(resp.Body).Decode(attributes)
+	if err != nil {
+		return nil, err
+	}
+	attributesCache.set(placeID, attributes)
+	return attributes, nil
+}
+
+// placeAttributes combines Google's attributes with approved community tags.
+// A community tag wins over Google, since it has been moderated and is
+// usually the fresher of the two.
+func placeAttributes(ctx context.Context, result BiteResult) *PlaceAttributes {
+	attributes := &PlaceAttributes{}
+	if !strings.HasPrefix(result.PlaceID, foursquareIDPrefix) && !strings.HasPrefix(result.PlaceID, fixtureIDPrefix) {
+		fetched, err := fetchPlaceAttributes(ctx, result.PlaceID)
+		if err != nil {
+			logDetailsError(err)
+		} else {
+			copied := *fetched
+			attributes = &copied
+		}
+	}
+	yes := true
+	if contains(result.CommunityTags, "good-for-kids") {
+		attributes.GoodForChildren = &yes
+	}
+	if contains(result.CommunityTags, "allows-dogs") {
+		attributes.AllowsDogs = &yes
+	}
+	return attributes
+}
+
+func enrichAttributes(ctx context.Context, biteArray *BiteResponse) {
+	sem := make(chan struct{}, attributesConcurrency)
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		wg.Add(1)
+		go func(result *BiteResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() == nil {
+				result.Attributes = placeAttributes(ctx, *result)
+			}
+		}(&biteArray.Results[i])
+	}
+	wg.Wait()
+}
+
+// excludeBars drops results whose primary type is a bar or club. Without
+// fetched attributes the first search type stands in for the primary type.
+func excludeBars(biteArray *BiteResponse) {
+	kept := make([]BiteResult, 0, len(biteArray.Results))
+	for _, result := range biteArray.Results {
+		primary := ""
+		if result.Attributes != nil && result.Attributes.PrimaryType != "" {
+			primary = result.Attributes.PrimaryType
+		} else if len(result.Types) > 0 {
+			primary = result.Types[0]
+		}
+		if !contains(barTypes, primary) {
+			kept = append(kept, result)
+		}
+	}
+	biteArray.Results = kept
+}
+
+// filterAttribute keeps results whose attribute is known to be true. As with
+// accessibility, unknown counts as no.
+func filterAttribute(biteArray *BiteResponse, attribute func(*PlaceAttributes) *bool) {
+	kept := make([]BiteResult, 0, len(biteArray.Results))
+	for _, result := range biteArray.Results {
+		if result.Attributes == nil {
+			continue
+		}
+		if v := attribute(result.Attributes); v != nil && *v {
+			kept = append(kept, result)
+		}
+	}
+	biteArray.Results = kept
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const maxBatchPlaces = 25
+
+var batchSizeError = &BodyError{Error: "must list between 1 and 25 place IDs", Field: "placeIds"}
+
+type PlacesBatch struct {
+	Places  []*PlaceSummary `json:"places"`
+	Missing []string        `json:"missing,omitempty"`
+}
+
+// handlePlacesBatch hydrates up to maxBatchPlaces place IDs in one call for
+// screens that hold lists of saved places. IDs that fail to load, or that
+// Google no longer knows, are reported in Missing rather than failing the
+// batch.
+func handlePlacesBatch(placeIDs []string) (events.APIGatewayProxyResponse, error) {
+	if len(placeIDs) == 0 || len(placeIDs) > maxBatchPlaces {
+		return badRequest(batchSizeError)
+	}
+	batch := PlacesBatch{Places: []*PlaceSummary{}}
+	for i, summary := range lookupSummaries(placeIDs) {
+		if summary == nil || summary.NotFound {
+			batch.Missing = append(batch.Missing, placeIDs[i])
+			continue
+		}
+		batch.Places = append(batch.Places, summary)
+	}
+	return jsonSuccess(batch)
+}
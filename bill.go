@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+const (
+	maxGroupSize = 50
+	// billExtras widens the estimate from an entrée alone to one with a drink,
+	// side or share of a starter on top.
+	billExtras = 1.4
+
+	mealPriceFromMenu       = "menu"
+	mealPriceFromPriceLevel = "priceLevel"
+)
+
+// billRegion holds what a meal costs on top of the menu price in a country,
+// or a country subdivision such as "US-NY", plus typical entrée prices per
+// Google price level for places without a menu.
+type billRegion struct {
+	Tax         float64   `json:"tax"`
+	Tip         float64   `json:"tip"`
+	Currency    string    `json:"currency"`
+	PriceLevels []float64 `json:"priceLevels"`
+}
+
+var defaultBillRegions = map[string]billRegion{
+	"US":    {Tax: 0.08, Tip: 0.18, Currency: "USD", PriceLevels: []float64{12, 22, 40, 75}},
+	"US-CA": {Tax: 0.0875, Tip: 0.18, Currency: "USD", PriceLevels: []float64{14, 25, 45, 85}},
+	"US-NY": {Tax: 0.08875, Tip: 0.2, Currency: "USD", PriceLevels: []float64{14, 25, 45, 90}},
+	"CA":    {Tax: 0.13, Tip: 0.15, Currency: "CAD", PriceLevels: []float64{15, 28, 50, 90}},
+	"GB":    {Tax: 0, Tip: 0.1, Currency: "GBP", PriceLevels: []float64{10, 18, 35, 65}},
+	"IE":    {Tax: 0, Tip: 0.1, Currency: "EUR", PriceLevels: []float64{12, 22, 38, 70}},
+	"FR":    {Tax: 0, Tip: 0.05, Currency: "EUR", PriceLevels: []float64{12, 22, 40, 80}},
+	"DE":    {Tax: 0, Tip: 0.1, Currency: "EUR", PriceLevels: []float64{11, 20, 35, 70}},
+	"ES":    {Tax: 0, Tip: 0.05, Currency: "EUR", PriceLevels: []float64{10, 18, 32, 65}},
+	"AU":    {Tax: 0, Tip: 0.05, Currency: "AUD", PriceLevels: []float64{18, 30, 55, 100}},
+	"JP":    {Tax: 0, Tip: 0, Currency: "JPY", PriceLevels: []float64{1000, 2500, 6000, 15000}},
+}
+
+// billRegions is defaultBillRegions with BILL_DEFAULTS merged over it, e.g.
+// {"US-WA": {"tax": 0.101, "tip": 0.2, "currency": "USD", "priceLevels": [13, 24, 42, 80]}}.
+var billRegions struct {
+	once    sync.Once
+	regions map[string]billRegion
+}
+
+func loadBillRegions(raw string) map[string]billRegion {
+	regions := map[string]billRegion{}
+	for code, region := range defaultBillRegions {
+		regions[code] = region
+	}
+	var overrides map[string]billRegion
+	if raw != "" && json.Unmarshal([]byte(raw), &overrides) == nil {
+		for code, region := range overrides {
+			regions[code] = region
+		}
+	}
+	return regions
+}
+
+// billRegionFor prefers the address's subdivision over its country.
+func billRegionFor(address *StructuredAddress) (string, billRegion, bool) {
+	billRegions.once.Do(func() {
+		billRegions.regions = loadBillRegions(cfg.BillDefaults)
+	})
+	if address == nil || address.CountryCode == "" {
+		return "", billRegion{}, false
+	}
+	if address.RegionCode != "" {
+		code := address.CountryCode + "-" + address.RegionCode
+		if region, ok := billRegions.regions[code]; ok {
+			return code, region, true
+		}
+	}
+	region, ok := billRegions.regions[address.CountryCode]
+	return address.CountryCode, region, ok
+}
+
+// priceLevelMealPrice is the fallback entrée price for a place with no menu.
+func priceLevelMealPrice(priceLevel int, region billRegion) *MealPrice {
+	if priceLevel < 1 || priceLevel > len(region.PriceLevels) {
+		return nil
+	}
+	return &MealPrice{Amount: region.PriceLevels[priceLevel-1], Currency: region.Currency, Source: mealPriceFromPriceLevel}
+}
+
+type PriceRange struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+type BillEstimate struct {
+	PlaceID   string     `json:"placeId"`
+	GroupSize int        `json:"groupSize"`
+	Region    string     `json:"region"`
+	MealPrice *MealPrice `json:"mealPrice"`
+	Tax       float64    `json:"tax"`
+	Tip       float64    `json:"tip"`
+	PerPerson PriceRange `json:"perPerson"`
+	Total     PriceRange `json:"total"`
+}
+
+func newBillEstimate(placeID string, groupSize int, code string, region billRegion, meal *MealPrice) BillEstimate {
+	low := meal.Amount * (1 + region.Tax + region.Tip)
+	perPerson := PriceRange{Low: roundCents(low), High: roundCents(low * billExtras)}
+	return BillEstimate{
+		PlaceID:   placeID,
+		GroupSize: groupSize,
+		Region:    code,
+		MealPrice: meal,
+		Tax:       region.Tax,
+		Tip:       region.Tip,
+		PerPerson: perPerson,
+		Total:     PriceRange{Low: roundCents(perPerson.Low * float64(groupSize)), High: roundCents(perPerson.High * float64(groupSize))},
+	}
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// handleSplitBill estimates what each of groupSize people will pay at a
+// place: the menu's entrée price, or the region's typical price for the
+// place's price level, plus the region's tax and tip.
+func handleSplitBill(ctx context.Context, placeID string, groupSize int) (events.APIGatewayProxyResponse, error) {
+	if placeID == "" || groupSize < 1 || groupSize > maxGroupSize {
+		return clientError(http.StatusBadRequest)
+	}
+	place, err := respondPlaceDetails(placeID,
+		maps.PlaceDetailsFieldMaskPlaceID,
+		maps.PlaceDetailsFieldMaskWebsite,
+		maps.PlaceDetailsFieldMaskAddressComponent,
+		maps.PlaceDetailsFieldMaskPriceLevel,
+	)
+	if err != nil {
+		return detailsFailure(err)
+	}
+	code, region, known := billRegionFor(parseAddressComponents(place.AddressComponents))
+	meal := placeMealPrice(ctx, place)
+	if meal == nil && known {
+		meal = priceLevelMealPrice(place.PriceLevel, region)
+	}
+	if meal == nil {
+		return clientError(http.StatusNotFound)
+	}
+	return jsonSuccess(newBillEstimate(placeID, groupSize, code, region, meal))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	confidenceHigh   = "high"
+	confidenceMedium = "medium"
+	confidenceLow    = "low"
+
+	// confidentMenuItems is how many priced entrées a menu needs before its
+	// median is trusted over a handful of outliers.
+	confidentMenuItems = 5
+)
+
+var regionCache = newTTLCache(24 * time.Hour)
+
+type PerPersonEstimate struct {
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency,omitempty"`
+	Source     string  `json:"source"`
+	Confidence string  `json:"confidence"`
+}
+
+// regionAt reverse geocodes a point to the address used for billRegionFor.
+// Results in a search are close together, so one lookup covers them all.
+func regionAt(ctx context.Context, point maps.LatLng) *StructuredAddress {
+	key := fmt.Sprintf("%.2f,%.2f", math.Round(point.Lat*100)/100, math.Round(point.Lng*100)/100)
+	if cached, ok := regionCache.get(key); ok {
+		return cached.(*StructuredAddress)
+	}
+	client, err := googleClient()
+	if err != nil {
+		errorLogger.Println(err)
+		return nil
+	}
+	results, err := client.ReverseGeocode(ctx, &maps.GeocodingRequest{
+		LatLng:     &point,
+		ResultType: []string{"administrative_area_level_1", "country"},
+	})
+	if err != nil {
+		errorLogger.Println(err)
+		return nil
+	}
+	var address *StructuredAddress
+	if len(results) > 0 {
+		address = parseAddressComponents(results[0].AddressComponents)
+	}
+	regionCache.set(key, address)
+	return address
+}
+
+// perPersonEstimate is what one diner pays with tax and tip, from the menu
+// when there is one in the region's currency and from the price level
+// otherwise.
+func perPersonEstimate(meal *MealPrice, priceLevel int, region billRegion) *PerPersonEstimate {
+	if meal != nil && meal.Currency != "" && meal.Currency != region.Currency {
+		meal = nil
+	}
+	confidence := confidenceMedium
+	if meal != nil && meal.Items >= confidentMenuItems {
+		confidence = confidenceHigh
+	}
+	if meal == nil {
+		meal = priceLevelMealPrice(priceLevel, region)
+		confidence = confidenceLow
+	}
+	if meal == nil {
+		return nil
+	}
+	return &PerPersonEstimate{
+		Amount:     roundCents(meal.Amount * (1 + region.Tax + region.Tip)),
+		Currency:   region.Currency,
+		Source:     meal.Source,
+		Confidence: confidence,
+	}
+}
+
+// filterBudget keeps results estimated to cost at most maxPerPerson each,
+// annotating every kept result with its estimate. Menus are only fetched for
+// the first few results, as in enrichMealPrices. Results with no estimate at
+// all are dropped. When the region is unknown, or couldn't be looked up,
+// nothing can be estimated, so results are left unfiltered and the meta's
+// budgetConfidence says so.
+func filterBudget(ctx context.Context, biteArray *BiteResponse, maxPerPerson float64, origin maps.LatLng) {
+	_, region, ok := billRegionFor(regionAt(ctx, origin))
+	if !ok {
+		if biteArray.Meta != nil {
+			biteArray.Meta.BudgetConfidence = confidenceLow
+		}
+		return
+	}
+	sem := make(chan struct{}, mealPriceConcurrency)
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		wg.Add(1)
+		go func(i int, result *BiteResult) {
+			defer wg.Done()
+			meal := result.EstimatedMealPrice
+			if meal == nil && i < mealPriceEnrichLimit {
+				sem <- struct{}{}
+				meal = mealPriceFor(ctx, result.PlaceID)
+				<-sem
+			}
+			result.PerPerson = perPersonEstimate(meal, result.PriceLevel, region)
+		}(i, &biteArray.Results[i])
+	}
+	wg.Wait()
+	kept := make([]BiteResult, 0, len(biteArray.Results))
+	for _, result := range biteArray.Results {
+		if result.PerPerson != nil && result.PerPerson.Amount <= maxPerPerson {
+			kept = append(kept, result)
+		}
+	}
+	biteArray.Results = kept
+}
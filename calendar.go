@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+const (
+	icsTimeFormat          = "20060102T150405Z"
+	localTimeFormat        = "2006-01-02T15:04:05"
+	defaultEventMinutes    = 90
+	icsMaxLineOctets       = 75
+	calendarProductID      = "-//Knowledge Labz//Bite//EN"
+	calendarSummaryPattern = "Dinner at %s"
+)
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func handleCalendar(placeID, startTime string, durationMinutes int) (events.APIGatewayProxyResponse, error) {
+	if placeID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	if durationMinutes <= 0 {
+		durationMinutes = defaultEventMinutes
+	}
+	place, err := respondPlaceDetails(placeID,
+		maps.PlaceDetailsFieldMaskName,
+		maps.PlaceDetailsFieldMaskFormattedAddress,
+		maps.PlaceDetailsFieldMaskGeometry,
+		maps.PlaceDetailsFieldMaskURL,
+		maps.PlaceDetailsFieldMaskUTCOffset,
+	)
+	if err != nil {
+		return detailsFailure(err)
+	}
+	start, err := parseStartTime(startTime, place)
+	if err != nil {
+		return clientError(http.StatusBadRequest)
+	}
+	body := placeEvent(place, start, time.Duration(durationMinutes)*time.Minute)
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":                  "text/calendar; charset=utf-8",
+			"Content-Disposition":           `attachment; filename="bite.ics"`,
+			"Access-Control-Allow-Origin":   "*",
+			"Access-Control-Expose-Headers": "Content-Disposition",
+		},
+		IsBase64Encoded: false,
+		Body:            body,
+	}, nil
+}
+
+// parseStartTime accepts RFC 3339, or a local time without an offset which is
+// read in the restaurant's time zone.
+func parseStartTime(startTime string, place maps.PlaceDetailsResult) (time.Time, error) {
+	start, err := time.Parse(time.RFC3339, startTime)
+	if err == nil {
+		return start, nil
+	}
+	return time.ParseInLocation(localTimeFormat, startTime, placeTimezone(context.Background(), place))
+}
+
+func placeEvent(place maps.PlaceDetailsResult, start time.Time, duration time.Duration) string {
+	mapLink := place.URL
+	if mapLink == "" {
+		mapLink = placeMapsURL(place.Name, place.PlaceID)
+	}
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + calendarProductID,
+		"METHOD:PUBLISH",
+		"BEGIN:VEVENT",
+		fmt.Sprintf("UID:%s-%d@bite", place.PlaceID, start.Unix()),
+		"DTSTAMP:" + time.Now().UTC().Format(icsTimeFormat),
+		"DTSTART:" + start.UTC().Format(icsTimeFormat),
+		"DTEND:" + start.Add(duration).UTC().Format(icsTimeFormat),
+		"SUMMARY:" + icsEscaper.Replace(fmt.Sprintf(calendarSummaryPattern, place.Name)),
+		"LOCATION:" + icsEscaper.Replace(place.FormattedAddress),
+		fmt.Sprintf("GEO:%f;%f", place.Geometry.Location.Lat, place.Geometry.Location.Lng),
+		"URL:" + mapLink,
+		"DESCRIPTION:" + icsEscaper.Replace("Map: "+mapLink),
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// foldICSLine splits content lines longer than 75 octets as RFC 5545
+// requires, without cutting through a multi-byte rune.
+func foldICSLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > icsMaxLineOctets {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	filterOpenNow    = "openNow"
+	filterPrice      = "price"
+	filterRadius     = "radius"
+	filterPagination = "pagination"
+	filterKeyword    = "keyword"
+	filterMealType   = "mealType"
+
+	filterNative   = "native"
+	filterEmulated = "emulated"
+	filterSkipped  = "skipped"
+)
+
+// providerCapabilities says how a provider handles each filter. Filters a
+// provider can't apply itself are emulated over its results where the data
+// allows it, and anything missing from the map is skipped.
+type providerCapabilities map[string]string
+
+var googleCapabilities = providerCapabilities{
+	filterKeyword:    filterNative,
+	filterMealType:   filterNative,
+	filterOpenNow:    filterNative,
+	filterPrice:      filterNative,
+	filterRadius:     filterNative,
+	filterPagination: filterNative,
+}
+
+var osmCapabilities = providerCapabilities{
+	filterKeyword:  filterEmulated,
+	filterMealType: filterNative,
+	filterRadius:   filterNative,
+}
+
+var foursquareCapabilities = providerCapabilities{
+	filterKeyword:  filterNative,
+	filterMealType: filterNative,
+	filterOpenNow:  filterNative,
+	filterPrice:    filterNative,
+	filterRadius:   filterNative,
+}
+
+func requestedFilters(params searchParams) []string {
+	filters := []string{filterPagination}
+	if !params.IgnoreOpenNow {
+		filters = append(filters, filterOpenNow)
+	}
+	if params.MinPrice > 0 || (params.MaxPrice > 0 && params.MaxPrice < 5) {
+		filters = append(filters, filterPrice)
+	}
+	if params.Radius > 0 {
+		filters = append(filters, filterRadius)
+	}
+	if params.Keyword != "" {
+		filters = append(filters, filterKeyword)
+	}
+	if params.MealType != "" {
+		filters = append(filters, filterMealType)
+	}
+	return filters
+}
+
+// applyCapabilities post-filters results for emulated filters and reports
+// how each requested filter was handled.
+func applyCapabilities(capabilities providerCapabilities, params searchParams, resp *maps.PlacesSearchResponse) map[string]string {
+	report := map[string]string{}
+	for _, filter := range requestedFilters(params) {
+		mode, ok := capabilities[filter]
+		if !ok {
+			mode = filterSkipped
+		}
+		report[filter] = mode
+		if mode != filterEmulated {
+			continue
+		}
+		kept := resp.Results[:0]
+		for _, result := range resp.Results {
+			if matchesFilter(filter, params, result) {
+				kept = append(kept, result)
+			}
+		}
+		resp.Results = kept
+	}
+	return report
+}
+
+func matchesFilter(filter string, params searchParams, result maps.PlacesSearchResult) bool {
+	switch filter {
+	case filterOpenNow:
+		return result.OpeningHours == nil || result.OpeningHours.OpenNow == nil || *result.OpeningHours.OpenNow
+	case filterPrice:
+		if result.PriceLevel == 0 {
+			return true
+		}
+		if params.MinPrice > 0 && result.PriceLevel < params.MinPrice {
+			return false
+		}
+		return params.MaxPrice <= 0 || params.MaxPrice >= 5 || result.PriceLevel <= params.MaxPrice
+	case filterRadius:
+		return distanceMeters(maps.LatLng{Lat: params.Lat, Lng: params.Long}, result.Geometry.Location) <= float64(params.Radius)
+	case filterKeyword:
+		keyword := strings.ToLower(params.Keyword)
+		if strings.Contains(strings.ToLower(result.Name), keyword) {
+			return true
+		}
+		for _, t := range result.Types {
+			if strings.Contains(strings.ReplaceAll(t, "_", " "), keyword) {
+				return true
+			}
+		}
+		return false
+	case filterMealType:
+		return matchesMealType(params.MealType, result.Types)
+	default:
+		return true
+	}
+}
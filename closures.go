@@ -0,0 +1,29 @@
+package main
+
+const (
+	businessClosedPermanently = "CLOSED_PERMANENTLY"
+	businessClosedTemporarily = "CLOSED_TEMPORARILY"
+)
+
+// filterClosures drops permanently closed places unless includeClosed is set,
+// drops temporarily closed ones when excludeTemporary is set, and flags the
+// temporarily closed places that remain. It builds a new slice, so pages
+// shared through pageCache are left intact.
+func filterClosures(biteArray *BiteResponse, includeClosed, excludeTemporary bool) {
+	kept := make([]BiteResult, 0, len(biteArray.Results))
+	for _, result := range biteArray.Results {
+		switch result.BusinessStatus {
+		case businessClosedPermanently:
+			if !includeClosed {
+				continue
+			}
+		case businessClosedTemporarily:
+			if excludeTemporary {
+				continue
+			}
+			result.TemporarilyClosed = true
+		}
+		kept = append(kept, result)
+	}
+	biteArray.Results = kept
+}
@@ -0,0 +1,240 @@
+// Command loadtest drives a biteAPI endpoint with a mix of verbs and reports
+// latency percentiles per verb.
+//
+//	go run ./cmd/loadtest -url https://api.example.com/bite -scenario search -c 20 -d 1m
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type scenario map[string]int
+
+var scenarios = map[string]scenario{
+	"search": {"create": 80, "nextpage": 20},
+	"photo":  {"create": 20, "photo": 80},
+	"mixed":  {"create": 50, "nextpage": 20, "photo": 30},
+}
+
+type sample struct {
+	verb    string
+	status  int
+	latency time.Duration
+}
+
+type worker struct {
+	client   *http.Client
+	url      string
+	apiKey   string
+	secret   string
+	lat      float64
+	long     float64
+	radius   uint
+	mu       sync.Mutex
+	tokens   []string
+	photoRef []string
+}
+
+func main() {
+	endpoint := flag.String("url", "http://localhost:3000/", "endpoint to POST requests to")
+	apiKey := flag.String("api-key", os.Getenv("BITE_API_KEY"), "value for the X-Api-Key header")
+	secret := flag.String("device-secret", os.Getenv("BITE_DEVICE_SECRET"), "DEVICE_SECRET to sign requests with, if the endpoint requires signatures")
+	name := flag.String("scenario", "mixed", "request mix: search, photo or mixed")
+	concurrency := flag.Int("c", 10, "concurrent workers")
+	duration := flag.Duration("d", 30*time.Second, "how long to run")
+	lat := flag.Float64("lat", 39.7527, "search latitude")
+	long := flag.Float64("long", -104.9995, "search longitude")
+	radius := flag.Uint("radius", 1500, "search radius in meters")
+	flag.Parse()
+
+	mix, ok := scenarios[*name]
+	if !ok {
+		log.Fatalf("unknown scenario %q", *name)
+	}
+	w := &worker{
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    *endpoint,
+		apiKey: *apiKey,
+		secret: *secret,
+		lat:    *lat,
+		long:   *long,
+		radius: *radius,
+	}
+	samples := make(chan sample, 1024)
+	deadline := time.Now().Add(*duration)
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for time.Now().Before(deadline) {
+				samples <- w.do(pick(mix, rng), rng)
+			}
+		}(int64(i))
+	}
+	go func() {
+		wg.Wait()
+		close(samples)
+	}()
+
+	byVerb := map[string][]sample{}
+	for s := range samples {
+		byVerb[s.verb] = append(byVerb[s.verb], s)
+	}
+	report(byVerb, *duration)
+}
+
+func pick(mix scenario, rng *rand.Rand) string {
+	total := 0
+	for _, weight := range mix {
+		total += weight
+	}
+	n := rng.Intn(total)
+	verbs := make([]string, 0, len(mix))
+	for verb := range mix {
+		verbs = append(verbs, verb)
+	}
+	sort.Strings(verbs)
+	for _, verb := range verbs {
+		if n < mix[verb] {
+			return verb
+		}
+		n -= mix[verb]
+	}
+	return "create"
+}
+
+// do sends one request for verb. Page tokens and photo references come from
+// earlier search responses, so those verbs fall back to a search until one
+// has completed.
+func (w *worker) do(verb string, rng *rand.Rand) sample {
+	body := map[string]interface{}{"verb": "create", "lat": w.lat, "long": w.long, "radius": w.radius}
+	w.mu.Lock()
+	switch {
+	case verb == "nextpage" && len(w.tokens) > 0:
+		body = map[string]interface{}{"verb": verb, "pageToken": w.tokens[rng.Intn(len(w.tokens))]}
+	case verb == "photo" && len(w.photoRef) > 0:
+		body = map[string]interface{}{"verb": verb, "photoRef": w.photoRef[rng.Intn(len(w.photoRef))]}
+	default:
+		verb = "create"
+	}
+	w.mu.Unlock()
+
+	payload, _ := json.Marshal(body)
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.apiKey != "" {
+		req.Header.Set("X-Api-Key", w.apiKey)
+	}
+	if w.secret != "" {
+		sign(req, w.secret, payload)
+	}
+	start := time.Now()
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return sample{verb: verb, status: 0, latency: time.Since(start)}
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	s := sample{verb: verb, status: resp.StatusCode, latency: time.Since(start)}
+	if verb == "create" && resp.StatusCode == http.StatusOK {
+		w.remember(data)
+	}
+	return s
+}
+
+// sign adds the device signature headers the endpoint checks when
+// DEVICE_SECRET is set. Nonces come from crypto/rand: the workers' seeded
+// generators would repeat them from run to run and be rejected as replays.
+func sign(req *http.Request, secret string, body []byte) {
+	raw := make([]byte, 16)
+	crand.Read(raw)
+	nonce := hex.EncodeToString(raw)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n" + string(body)))
+	req.Header.Set("X-Bite-Timestamp", timestamp)
+	req.Header.Set("X-Bite-Nonce", nonce)
+	req.Header.Set("X-Bite-Signature", hex.EncodeToString(mac.Sum(nil)))
+}
+
+func (w *worker) remember(data []byte) {
+	var parsed struct {
+		Results []struct {
+			Photos []struct {
+				PhotoReference string `json:"photo_reference"`
+			} `json:"photos"`
+		}
+		NextPageToken string
+	}
+	if json.Unmarshal(data, &parsed) != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if parsed.NextPageToken != "" && len(w.tokens) < 100 {
+		w.tokens = append(w.tokens, parsed.NextPageToken)
+	}
+	for _, result := range parsed.Results {
+		if len(result.Photos) > 0 && len(w.photoRef) < 500 {
+			w.photoRef = append(w.photoRef, result.Photos[0].PhotoReference)
+		}
+	}
+}
+
+func report(byVerb map[string][]sample, duration time.Duration) {
+	verbs := make([]string, 0, len(byVerb))
+	for verb := range byVerb {
+		verbs = append(verbs, verb)
+	}
+	sort.Strings(verbs)
+	fmt.Printf("%-10s %8s %8s %10s %10s %10s %10s  %s\n", "verb", "count", "rps", "p50", "p90", "p99", "max", "statuses")
+	for _, verb := range verbs {
+		samples := byVerb[verb]
+		latencies := make([]time.Duration, len(samples))
+		statuses := map[int]int{}
+		for i, s := range samples {
+			latencies[i] = s.latency
+			statuses[s.status]++
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		fmt.Printf("%-10s %8d %8.1f %10s %10s %10s %10s  %v\n",
+			verb,
+			len(samples),
+			float64(len(samples))/duration.Seconds(),
+			percentile(latencies, 50),
+			percentile(latencies, 90),
+			percentile(latencies, 99),
+			latencies[len(latencies)-1].Round(time.Millisecond),
+			statuses,
+		)
+	}
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100
+	if i > 0 {
+		i--
+	}
+	return sorted[i].Round(time.Millisecond)
+}
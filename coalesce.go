@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const leasePollInterval = 100 * time.Millisecond
+
+// flightGroup runs one call per key at a time; callers that arrive while it
+// is in flight wait for it and share its result.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done   chan struct{}
+	result providerResult
+}
+
+func (g *flightGroup) do(key string, fn func() providerResult) (providerResult, bool) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = map[string]*flight{}
+	}
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		<-f.done
+		return f.result, true
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	f.result = fn()
+	g.mu.Lock()
+	delete(g.flights, key)
+	g.mu.Unlock()
+	close(f.done)
+	return f.result, false
+}
+
+var searchFlights flightGroup
+
+var leaseOwner struct {
+	once sync.Once
+	id   string
+}
+
+// containerLeaseOwner identifies this container's leases in the shared cache.
+func containerLeaseOwner() string {
+	leaseOwner.once.Do(func() {
+		id := make([]byte, 8)
+		rand.Read(id)
+		leaseOwner.id = hex.EncodeToString(id)
+	})
+	return leaseOwner.id
+}
+
+// coalescedSearch keeps a hot area from stampeding the provider. Within the
+// container, identical concurrent searches share one call. Across containers,
+// whoever holds the shared lease calls the provider while the rest wait
+// briefly for its results to land in the search cache.
+func coalescedSearch(ctx context.Context, p placesProvider, params searchParams) providerResult {
+	key := searchKey("search", p.Name(), params)
+	r, shared := searchFlights.do(key, func() providerResult {
+		return leasedSearch(ctx, p, params, key)
+	})
+	if shared {
+		emitMetric("SearchCoalesced", "Count", 1)
+		r.resp.Results = append([]maps.PlacesSearchResult(nil), r.resp.Results...)
+	}
+	return r
+}
+
+// leaseFailure is left by a lease holder whose provider call failed, so the
+// containers waiting on it fall back instead of waiting out the lease.
+type leaseFailure struct {
+	Error string `json:"error"`
+}
+
+var leaseFailures struct {
+	once  sync.Once
+	cache *layeredCache
+}
+
+func leaseFailureCache() *layeredCache {
+	leaseFailures.once.Do(func() {
+		leaseFailures.cache = newLayeredCache("leasefailure", cfg.CacheLeaseTTL)
+	})
+	return leaseFailures.cache
+}
+
+func leasedSearch(ctx context.Context, p placesProvider, params searchParams, key string) providerResult {
+	l2 := configuredCacheL2()
+	if l2 == nil || cfg.SearchCacheTTL <= 0 {
+		return callProvider(ctx, p, params)
+	}
+	leaseKey := cacheKey("lease", key)
+	owner := containerLeaseOwner()
+	held, err := l2.Lease(ctx, leaseKey, owner, cfg.CacheLeaseTTL)
+	if err != nil {
+		errorLogger.Println(err)
+		return callProvider(ctx, p, params)
+	}
+	if held {
+		defer func() {
+			err := l2.Release(ctx, leaseKey, owner)
+			if err != nil {
+				errorLogger.Println(err)
+			}
+		}()
+		r := callProvider(ctx, p, params)
+		if r.err != nil || r.dataAsOf != nil {
+			failure := leaseFailure{Error: errCircuitOpen.Error()}
+			if r.err != nil {
+				failure.Error = r.err.Error()
+			}
+			leaseFailureCache().set(ctx, searchKey("leasefailure", p.Name(), params), failure)
+		}
+		return r
+	}
+	emitMetric("SearchLeaseContended", "Count", 1)
+	if r, ok := awaitRefresh(ctx, p, params); ok {
+		return r
+	}
+	return callProvider(ctx, p, params)
+}
+
+// awaitRefresh waits, up to CACHE_LEASE_WAIT, for the lease holder's results
+// to reach the search cache. When the holder's call failed, the area's last
+// good results are served as stale, the same as the holder did.
+func awaitRefresh(ctx context.Context, p placesProvider, params searchParams) (providerResult, bool) {
+	start := time.Now()
+	deadline := start.Add(cfg.CacheLeaseWait)
+	for {
+		if r, ok := cachedResults(ctx, p, params); ok {
+			r.timing = newProviderTiming(r, time.Since(start))
+			return r, true
+		}
+		if r, ok := cachedNegative(ctx, p, params); ok {
+			return r, true
+		}
+		var failure leaseFailure
+		if leaseFailureCache().get(ctx, searchKey("leasefailure", p.Name(), params), &failure) {
+			r := providerResult{name: p.Name(), err: errors.New(failure.Error)}
+			r.timing = newProviderTiming(r, time.Since(start))
+			return staleResult(ctx, r, params), true
+		}
+		if time.Now().Add(leasePollInterval).After(deadline) {
+			return providerResult{}, false
+		}
+		select {
+		case <-ctx.Done():
+			return providerResult{}, false
+		case <-time.After(leasePollInterval):
+		}
+	}
+}
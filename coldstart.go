@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"googlemaps.github.io/maps"
+)
+
+const metricsNamespace = "BiteAPI"
+
+var google struct {
+	client *maps.Client
+	err    error
+}
+
+// init runs the slow parts of cold start concurrently: the SSM settings fetch
+// and the DynamoDB client, then the shared Google client once its key is
+// known. Anything not needed by every invocation is left to first use.
+func init() {
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		cfg, cfgErr = loadSettings(os.LookupEnv)
+	}()
+	go func() {
+		defer wg.Done()
+		db = dynamodb.New(awsSession)
+	}()
+	wg.Wait()
+	google.client, google.err = maps.NewClient(maps.WithAPIKey(cfg.GoogleAPIKey))
+	emitMetric("ColdStartMs", "Milliseconds", float64(time.Since(start).Milliseconds()))
+}
+
+// googleClient returns the Google Maps client shared by every invocation in
+// this container.
+func googleClient() (*maps.Client, error) {
+	return google.client, google.err
+}
+
+// emitMetric writes a single value in CloudWatch embedded metric format, which
+// Lambda turns into a metric from the log line.
+func emitMetric(name, unit string, value float64) {
+	emitDimensionedMetric(name, unit, value, nil)
+}
+
+// emitDimensionedMetric is emitMetric with dimensions beyond Stage.
+func emitDimensionedMetric(name, unit string, value float64, dimensions map[string]string) {
+	keys := []string{"Stage"}
+	fields := map[string]interface{}{
+		"Stage": cfg.Stage,
+		name:    value,
+	}
+	for k, v := range dimensions {
+		keys = append(keys, k)
+		fields[k] = v
+	}
+	fields["_aws"] = map[string]interface{}{
+		"Timestamp": time.Now().UnixNano() / int64(time.Millisecond),
+		"CloudWatchMetrics": []map[string]interface{}{{
+			"Namespace":  metricsNamespace,
+			"Dimensions": [][]string{keys},
+			"Metrics":    []map[string]string{{"Name": name, "Unit": unit}},
+		}},
+	}
+	line, err := json.Marshal(fields)
+	if err == nil {
+		fmt.Println(string(line))
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// settings is every knob the service reads, loaded once at cold start. Each
+// field names its environment variable and optional default; parameters
+// under CONFIG_SSM_PATH with the same names override the environment.
+type settings struct {
+	Stage string `env:"STAGE" default:"unset"`
+
+	GoogleAPIKey     string        `env:"API_KEY"`
+	Providers        []string      `env:"PROVIDERS" default:"google"`
+	ProviderTimeout  time.Duration `env:"PROVIDER_TIMEOUT" default:"3s"`
+	BreakerFailures  int           `env:"BREAKER_FAILURES" default:"5"`
+	BreakerCooldown  time.Duration `env:"BREAKER_COOLDOWN" default:"30s"`
+	StaleMaxAge      time.Duration `env:"STALE_MAX_AGE" default:"1h"`
+	NegativeCacheTTL time.Duration `env:"NEGATIVE_CACHE_TTL" default:"1m"`
+	SearchCacheTTL   time.Duration `env:"SEARCH_CACHE_TTL" default:"2m"`
+	CacheL1Entries   int           `env:"CACHE_L1_ENTRIES" default:"1000"`
+	CacheL2          string        `env:"CACHE_L2"`
+	CacheTable       string        `env:"CACHE_TABLE"`
+	CacheLeaseTTL    time.Duration `env:"CACHE_LEASE_TTL" default:"5s"`
+	CacheLeaseWait   time.Duration `env:"CACHE_LEASE_WAIT" default:"2s"`
+	RateLimitStore   string        `env:"RATE_LIMIT_STORE" default:"dynamodb"`
+	RedisAddr        string        `env:"REDIS_ADDR"`
+	RedisPassword    string        `env:"REDIS_PASSWORD"`
+	RedisTLS         bool          `env:"REDIS_TLS"`
+	RedisPoolSize    int           `env:"REDIS_POOL_SIZE" default:"10"`
+	FoursquareAPIKey string        `env:"FOURSQUARE_API_KEY"`
+	OverpassURL      string        `env:"OVERPASS_URL" default:"https://overpass-api.de/api/interpreter"`
+
+	APIKeysTable     string `env:"API_KEYS_TABLE"`
+	TenantsTable     string `env:"TENANTS_TABLE"`
+	DealsTable       string `env:"DEALS_TABLE"`
+	CampaignsTable   string `env:"CAMPAIGNS_TABLE"`
+	ListsTable       string `env:"LISTS_TABLE"`
+	EmbeddingsTable  string `env:"EMBEDDINGS_TABLE"`
+	PlaceTagsTable   string `env:"PLACE_TAGS_TABLE"`
+	SuggestionsTable string `env:"SUGGESTIONS_TABLE"`
+	ReportsTable     string `env:"REPORTS_TABLE"`
+
+	DeviceSecret string `env:"DEVICE_SECRET"`
+	ShareSecret  string `env:"SHARE_SECRET"`
+	ShareBaseURL string `env:"SHARE_BASE_URL"`
+	CursorSecret string `env:"CURSOR_SECRET"`
+
+	MenuPartnerURL string `env:"MENU_PARTNER_URL"`
+	MenuPartnerKey string `env:"MENU_PARTNER_KEY"`
+
+	InspectionsURL          string `env:"INSPECTIONS_SOCRATA_URL"`
+	InspectionsToken        string `env:"INSPECTIONS_SOCRATA_TOKEN"`
+	InspectionsNameField    string `env:"INSPECTIONS_NAME_FIELD" default:"dba"`
+	InspectionsAddressField string `env:"INSPECTIONS_ADDRESS_FIELD" default:"street"`
+	InspectionsScoreField   string `env:"INSPECTIONS_SCORE_FIELD" default:"score"`
+	InspectionsGradeField   string `env:"INSPECTIONS_GRADE_FIELD" default:"grade"`
+	InspectionsDateField    string `env:"INSPECTIONS_DATE_FIELD" default:"inspection_date"`
+
+	FaultInjection string `env:"FAULT_INJECTION"`
+
+	ProfanityFilter     string `env:"PROFANITY_FILTER" default:"moderate"`
+	CuisineTaxonomyPath string `env:"CUISINE_TAXONOMY_PATH"`
+	SummaryProvider     string `env:"SUMMARY_PROVIDER"`
+	SummaryModelID      string `env:"SUMMARY_MODEL_ID"`
+
+	PhotoModeration              string  `env:"PHOTO_MODERATION"`
+	PhotoModerationMinConfidence float64 `env:"PHOTO_MODERATION_MIN_CONFIDENCE" default:"80"`
+
+	PayloadLogSampleRate float64 `env:"PAYLOAD_LOG_SAMPLE_RATE"`
+	PayloadLogMaxBytes   int     `env:"PAYLOAD_LOG_MAX_BYTES" default:"8192"`
+
+	BillDefaults  string `env:"BILL_DEFAULTS"`
+	LateNightHour int    `env:"LATE_NIGHT_HOUR" default:"23"`
+}
+
+// cfg and cfgErr are populated during cold start; see coldstart.go.
+var cfg settings
+var cfgErr error
+
+func loadSettings(lookup func(string) (string, bool)) (settings, error) {
+	if prefix, ok := lookup("CONFIG_SSM_PATH"); ok && prefix != "" {
+		overrides, err := ssmParameters(prefix)
+		if err != nil {
+			return settings{}, fmt.Errorf("config: reading SSM parameters under %s: %w", prefix, err)
+		}
+		env := lookup
+		lookup = func(name string) (string, bool) {
+			if v, ok := overrides[name]; ok {
+				return v, true
+			}
+			return env(name)
+		}
+	}
+	var s settings
+	v := reflect.ValueOf(&s).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("env")
+		raw, ok := lookup(name)
+		if !ok || raw == "" {
+			raw = field.Tag.Get("default")
+		}
+		if raw == "" {
+			continue
+		}
+		err := setField(v.Field(i), raw)
+		if err != nil {
+			return settings{}, fmt.Errorf("config: %s: %w", name, err)
+		}
+	}
+	return s, nil
+}
+
+func setField(field reflect.Value, raw string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(raw)
+	case []string:
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+func ssmParameters(prefix string) (map[string]string, error) {
+	client := ssm.New(awsSession)
+	params := map[string]string{}
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(prefix),
+		WithDecryption: aws.Bool(true),
+	}
+	for {
+		out, err := client.GetParametersByPath(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range out.Parameters {
+			params[path.Base(aws.StringValue(p.Name))] = aws.StringValue(p.Value)
+		}
+		if out.NextToken == nil {
+			return params, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// validate reports every problem with the loaded settings at once, so a bad
+// deploy fails on its first invocation with the whole list.
+func (s settings) validate() error {
+	var problems []string
+	for _, name := range s.Providers {
+		if _, ok := registeredProviders[name]; !ok {
+			problems = append(problems, fmt.Sprintf("PROVIDERS: unknown provider %q", name))
+		}
+		if name == providerGoogle && s.GoogleAPIKey == "" {
+			problems = append(problems, "API_KEY is required for the google provider")
+		}
+		if name == providerFoursquare && s.FoursquareAPIKey == "" {
+			problems = append(problems, "FOURSQUARE_API_KEY is required for the foursquare provider")
+		}
+	}
+	if s.ProviderTimeout <= 0 {
+		problems = append(problems, "PROVIDER_TIMEOUT must be positive")
+	}
+	if s.DeviceSecret != "" && len(s.DeviceSecret) < 16 {
+		problems = append(problems, "DEVICE_SECRET must be at least 16 characters")
+	}
+	if s.ShareSecret != "" && len(s.ShareSecret) < 16 {
+		problems = append(problems, "SHARE_SECRET must be at least 16 characters")
+	}
+	if s.ShareSecret != "" && s.ShareBaseURL == "" {
+		problems = append(problems, "SHARE_BASE_URL is required when SHARE_SECRET is set")
+	}
+	if s.CursorSecret != "" && len(s.CursorSecret) < 16 {
+		problems = append(problems, "CURSOR_SECRET must be at least 16 characters")
+	}
+	if s.MenuPartnerURL != "" && s.MenuPartnerKey == "" {
+		problems = append(problems, "MENU_PARTNER_KEY is required when MENU_PARTNER_URL is set")
+	}
+	for name, raw := range map[string]string{
+		"OVERPASS_URL":            s.OverpassURL,
+		"SHARE_BASE_URL":          s.ShareBaseURL,
+		"MENU_PARTNER_URL":        s.MenuPartnerURL,
+		"INSPECTIONS_SOCRATA_URL": s.InspectionsURL,
+	} {
+		if raw == "" {
+			continue
+		}
+		if u, err := url.Parse(raw); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("%s is not an absolute URL: %q", name, raw))
+		}
+	}
+	if s.PhotoModeration != "" && s.PhotoModeration != moderationRekognition {
+		problems = append(problems, fmt.Sprintf("PHOTO_MODERATION: unknown moderator %q", s.PhotoModeration))
+	}
+	if _, ok := profanityLevels[s.ProfanityFilter]; !ok {
+		problems = append(problems, fmt.Sprintf("PROFANITY_FILTER must be off, severe, moderate or mild, got %q", s.ProfanityFilter))
+	}
+	if s.SummaryProvider != "" && s.SummaryProvider != digestBedrock {
+		problems = append(problems, fmt.Sprintf("SUMMARY_PROVIDER: unknown provider %q", s.SummaryProvider))
+	}
+	if s.SummaryProvider == digestBedrock && s.SummaryModelID == "" {
+		problems = append(problems, "SUMMARY_MODEL_ID is required for the bedrock summary provider")
+	}
+	if s.PayloadLogSampleRate < 0 || s.PayloadLogSampleRate > 1 {
+		problems = append(problems, "PAYLOAD_LOG_SAMPLE_RATE must be between 0 and 1")
+	}
+	if s.FaultInjection != "" {
+		var faults map[string]faultConfig
+		if err := json.Unmarshal([]byte(s.FaultInjection), &faults); err != nil {
+			problems = append(problems, fmt.Sprintf("FAULT_INJECTION: %s", err))
+		}
+	}
+	if s.CacheL1Entries < 1 {
+		problems = append(problems, "CACHE_L1_ENTRIES must be at least 1")
+	}
+	if s.CacheL2 != "" && s.CacheL2 != storeDynamoDB && s.CacheL2 != storeRedis {
+		problems = append(problems, fmt.Sprintf("CACHE_L2: unknown cache %q", s.CacheL2))
+	}
+	if s.CacheLeaseTTL < time.Second {
+		problems = append(problems, "CACHE_LEASE_TTL must be at least 1s")
+	}
+	if s.CacheLeaseWait < 0 || s.CacheLeaseWait > s.CacheLeaseTTL {
+		problems = append(problems, "CACHE_LEASE_WAIT must be between 0 and CACHE_LEASE_TTL")
+	}
+	if s.CacheL2 == storeDynamoDB && s.CacheTable == "" {
+		problems = append(problems, "CACHE_TABLE is required for the dynamodb cache")
+	}
+	if s.RateLimitStore != storeDynamoDB && s.RateLimitStore != storeRedis {
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_STORE must be dynamodb or redis, got %q", s.RateLimitStore))
+	}
+	if (s.CacheL2 == storeRedis || s.RateLimitStore == storeRedis) && s.RedisAddr == "" {
+		problems = append(problems, "REDIS_ADDR is required when CACHE_L2 or RATE_LIMIT_STORE is redis")
+	}
+	if s.RedisPoolSize < 1 {
+		problems = append(problems, "REDIS_POOL_SIZE must be at least 1")
+	}
+	if s.BreakerFailures < 1 {
+		problems = append(problems, "BREAKER_FAILURES must be at least 1")
+	}
+	if s.LateNightHour < 0 || s.LateNightHour > 23 {
+		problems = append(problems, "LATE_NIGHT_HOUR must be between 0 and 23")
+	}
+	if s.BillDefaults != "" {
+		var regions map[string]billRegion
+		if err := json.Unmarshal([]byte(s.BillDefaults), &regions); err != nil {
+			problems = append(problems, fmt.Sprintf("BILL_DEFAULTS: %s", err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	_ "embed"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed cuisines.yaml
+var defaultCuisineTaxonomy []byte
+
+type cuisineTaxonomy struct {
+	Version  int          `yaml:"version" json:"version"`
+	Cuisines []cuisineDef `yaml:"cuisines" json:"cuisines"`
+}
+
+type cuisineDef struct {
+	ID       string   `yaml:"id" json:"id"`
+	Label    string   `yaml:"label" json:"label"`
+	Dietary  bool     `yaml:"dietary" json:"-"`
+	Types    []string `yaml:"types" json:"-"`
+	Keywords []string `yaml:"keywords" json:"-"`
+
+	patterns []*regexp.Regexp
+}
+
+var cuisines struct {
+	once     sync.Once
+	taxonomy *cuisineTaxonomy
+}
+
+// loadedCuisines parses the taxonomy once per container, from
+// CUISINE_TAXONOMY_PATH when set and the bundled cuisines.yaml otherwise. A
+// bad override falls back to the bundled file.
+func loadedCuisines() *cuisineTaxonomy {
+	cuisines.once.Do(func() {
+		raw := defaultCuisineTaxonomy
+		if cfg.CuisineTaxonomyPath != "" {
+			override, err := os.ReadFile(cfg.CuisineTaxonomyPath)
+			if err != nil {
+				errorLogger.Println(err)
+			} else {
+				raw = override
+			}
+		}
+		taxonomy, err := parseCuisineTaxonomy(raw)
+		if err != nil {
+			errorLogger.Println(err)
+			taxonomy, _ = parseCuisineTaxonomy(defaultCuisineTaxonomy)
+		}
+		cuisines.taxonomy = taxonomy
+	})
+	return cuisines.taxonomy
+}
+
+func parseCuisineTaxonomy(raw []byte) (*cuisineTaxonomy, error) {
+	taxonomy := &cuisineTaxonomy{}
+	err := yaml.Unmarshal(raw, taxonomy)
+	if err != nil {
+		return nil, err
+	}
+	for i := range taxonomy.Cuisines {
+		def := &taxonomy.Cuisines[i]
+		for _, keyword := range def.Keywords {
+			def.patterns = append(def.patterns, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(keyword)+`\b`))
+		}
+	}
+	return taxonomy, nil
+}
+
+// classifyCuisines tags a result with canonical cuisine IDs from its types
+// and name, in taxonomy order.
+func classifyCuisines(result BiteResult) []string {
+	var ids []string
+	for _, def := range loadedCuisines().Cuisines {
+		if def.matches(result) {
+			ids = append(ids, def.ID)
+		}
+	}
+	return ids
+}
+
+func (def cuisineDef) matches(result BiteResult) bool {
+	for _, t := range result.Types {
+		for _, want := range def.Types {
+			if t == want {
+				return true
+			}
+		}
+	}
+	for _, pattern := range def.patterns {
+		if pattern.MatchString(result.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func cuisineLabelFor(id string) string {
+	for _, def := range loadedCuisines().Cuisines {
+		if def.ID == id {
+			return def.Label
+		}
+	}
+	return id
+}
+
+// classifyResults sets Cuisines on every result and, when filter is set,
+// keeps only results tagged with one of the comma-separated cuisine IDs.
+func classifyResults(biteArray *BiteResponse, filter string) {
+	var wanted []string
+	for _, id := range strings.Split(filter, ",") {
+		if id = strings.TrimSpace(strings.ToLower(id)); id != "" {
+			wanted = append(wanted, id)
+		}
+	}
+	kept := biteArray.Results[:0]
+	for _, result := range biteArray.Results {
+		result.Cuisines = classifyCuisines(result)
+		if len(wanted) == 0 || hasAny(result.Cuisines, wanted) {
+			kept = append(kept, result)
+		}
+	}
+	biteArray.Results = kept
+}
+
+func hasAny(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+const dealsConcurrency = 5
+
+var dealsCache = newTTLCache(5 * time.Minute)
+
+type Deal struct {
+	PlaceID     string `dynamodbav:"placeId" json:"placeId"`
+	DealID      string `dynamodbav:"dealId" json:"dealId"`
+	Title       string `dynamodbav:"title" json:"title"`
+	Description string `dynamodbav:"description" json:"description,omitempty"`
+	StartsAt    int64  `dynamodbav:"startsAt" json:"startsAt"`
+	EndsAt      int64  `dynamodbav:"endsAt" json:"endsAt"`
+	ExpiresAt   int64  `dynamodbav:"expiresAt" json:"-"`
+}
+
+func (d Deal) activeAt(t time.Time) bool {
+	return t.Unix() >= d.StartsAt && t.Unix() < d.EndsAt
+}
+
+func handleCreateDeal(ctx context.Context, placeID, title, description, startTime, endTime string) (events.APIGatewayProxyResponse, error) {
+	if cfg.DealsTable == "" || placeID == "" || title == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	start, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		return clientError(http.StatusBadRequest)
+	}
+	end, err := time.Parse(time.RFC3339, endTime)
+	if err != nil || !end.After(start) {
+		return clientError(http.StatusBadRequest)
+	}
+	id := make([]byte, 8)
+	_, err = rand.Read(id)
+	if err != nil {
+		return serverError(err)
+	}
+	deal := Deal{
+		PlaceID:     placeID,
+		DealID:      hex.EncodeToString(id),
+		Title:       sanitizeText(title),
+		Description: sanitizeText(description),
+		StartsAt:    start.Unix(),
+		EndsAt:      end.Unix(),
+		ExpiresAt:   end.Add(24 * time.Hour).Unix(),
+	}
+	item, err := dynamodbattribute.MarshalMap(deal)
+	if err != nil {
+		return serverError(err)
+	}
+	_, err = db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(cfg.DealsTable),
+		Item:      item,
+	})
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(deal)
+}
+
+func handleDeleteDeal(ctx context.Context, placeID, dealID string) (events.APIGatewayProxyResponse, error) {
+	if cfg.DealsTable == "" || placeID == "" || dealID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	_, err := db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(cfg.DealsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"placeId": {S: aws.String(placeID)},
+			"dealId":  {S: aws.String(dealID)},
+		},
+		ConditionExpression: aws.String("attribute_exists(dealId)"),
+	})
+	if isConditionalCheckFailed(err) {
+		return clientError(http.StatusNotFound)
+	}
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(map[string]string{"dealId": dealID, "status": "deleted"})
+}
+
+func dealsForPlace(ctx context.Context, placeID string) ([]Deal, error) {
+	if cached, ok := dealsCache.get(placeID); ok {
+		return cached.([]Deal), nil
+	}
+	out, err := db.QueryWithContext(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(cfg.DealsTable),
+		KeyConditionExpression: aws.String("placeId = :placeId"),
+		FilterExpression:       aws.String("endsAt > :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":placeId": {S: aws.String(placeID)},
+			":now":     {N: aws.String(fmt.Sprint(time.Now().Unix()))},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var deals []Deal
+	err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &deals)
+	if err != nil {
+		return nil, err
+	}
+	dealsCache.set(placeID, deals)
+	return deals, nil
+}
+
+// enrichDeals attaches deals running right now. Upcoming deals are cached
+// alongside active ones so a deal starting mid-cache still shows up.
+func enrichDeals(ctx context.Context, biteArray *BiteResponse) {
+	if cfg.DealsTable == "" {
+		return
+	}
+	now := time.Now()
+	sem := make(chan struct{}, dealsConcurrency)
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		wg.Add(1)
+		go func(result *BiteResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			deals, err := dealsForPlace(ctx, result.PlaceID)
+			if err != nil {
+				errorLogger.Println(err)
+				return
+			}
+			for _, deal := range deals {
+				if deal.activeAt(now) {
+					result.Deals = append(result.Deals, deal)
+				}
+			}
+		}(&biteArray.Results[i])
+	}
+	wg.Wait()
+}
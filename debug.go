@@ -0,0 +1,118 @@
+package main
+
+import "time"
+
+const (
+	cacheHit   = "hit"
+	cacheMiss  = "miss"
+	cacheStore = "store"
+)
+
+// SearchDebug explains how a search arrived at its results. It is only
+// returned to admin keys that ask for it with debug: true.
+type SearchDebug struct {
+	Scores    []ScoreBreakdown `json:"scores"`
+	Filters   []FilterStep     `json:"filters"`
+	Providers []ProviderTiming `json:"providers"`
+	Cache     []CacheDecision  `json:"cache,omitempty"`
+}
+
+// ScoreBreakdown shows the inputs to a result's Score: the rating evidence
+// from each source and the prior it was blended with.
+type ScoreBreakdown struct {
+	PlaceID     string                  `json:"placeId"`
+	Score       float64                 `json:"score"`
+	Ratings     map[string]SourceRating `json:"ratings"`
+	RatingSum   float64                 `json:"ratingSum"`
+	RatingCount float64                 `json:"ratingCount"`
+	PriorMean   float64                 `json:"priorMean"`
+	PriorCount  float64                 `json:"priorCount"`
+	Sponsored   bool                    `json:"sponsored,omitempty"`
+}
+
+// FilterStep records how many results a filter stage kept.
+type FilterStep struct {
+	Filter string `json:"filter"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+type ProviderTiming struct {
+	Provider string `json:"provider"`
+	Millis   int64  `json:"millis"`
+	Results  int    `json:"results"`
+	Error    string `json:"error,omitempty"`
+}
+
+type CacheDecision struct {
+	Cache   string `json:"cache"`
+	Outcome string `json:"outcome"`
+}
+
+func newProviderTiming(r providerResult, elapsed time.Duration) ProviderTiming {
+	timing := ProviderTiming{Provider: r.name, Millis: elapsed.Milliseconds(), Results: len(r.resp.Results)}
+	if r.err != nil {
+		timing.Error = r.err.Error()
+	}
+	return timing
+}
+
+// filtered records a filter stage. It is a no-op when debugging is off.
+func (d *SearchDebug) filtered(filter string, before, after int) {
+	if d == nil {
+		return
+	}
+	d.Filters = append(d.Filters, FilterStep{Filter: filter, Before: before, After: after})
+}
+
+func (d *SearchDebug) cached(cache, outcome string) {
+	if d == nil {
+		return
+	}
+	d.Cache = append(d.Cache, CacheDecision{Cache: cache, Outcome: outcome})
+}
+
+// explainScores fills in a breakdown for every result in its final order.
+func (d *SearchDebug) explainScores(biteArray BiteResponse) {
+	if d == nil {
+		return
+	}
+	d.Scores = make([]ScoreBreakdown, 0, len(biteArray.Results))
+	for _, result := range biteArray.Results {
+		sum, count := ratingEvidence(result)
+		d.Scores = append(d.Scores, ScoreBreakdown{
+			PlaceID:     result.PlaceID,
+			Score:       result.Score,
+			Ratings:     result.SourceRatings,
+			RatingSum:   sum,
+			RatingCount: count,
+			PriorMean:   ratingPriorMean,
+			PriorCount:  ratingPriorCount,
+			Sponsored:   result.Sponsored != nil,
+		})
+	}
+}
+
+// pageDebug returns meta with debug output added or removed for a request
+// served from a cached page. The meta is copied since the page is shared.
+func pageDebug(meta *ResponseMeta, debug bool, outcome string) *ResponseMeta {
+	var copied ResponseMeta
+	if meta != nil {
+		copied = *meta
+	}
+	if !debug {
+		copied.Debug = nil
+		if meta == nil {
+			return nil
+		}
+		return &copied
+	}
+	pageDebug := SearchDebug{}
+	if copied.Debug != nil {
+		pageDebug = *copied.Debug
+		pageDebug.Cache = append([]CacheDecision(nil), pageDebug.Cache...)
+	}
+	pageDebug.cached("page", outcome)
+	copied.Debug = &pageDebug
+	return &copied
+}
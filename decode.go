@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type BodyError struct {
+	Error string `json:"error"`
+	Field string `json:"field,omitempty"`
+}
+
+// decodeBody strictly decodes a request body: unknown fields, type
+// mismatches, syntax errors and trailing data are all rejected with the
+// offending field where one can be named.
+func decodeBody(body string) (BiteBody, *BodyError) {
+	var parameters BiteBody
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&parameters)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the JSON object")
+	}
+	if err == nil {
+		return parameters, nil
+	}
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, io.EOF):
+		return parameters, &BodyError{Error: "request body is empty"}
+	case errors.As(err, &typeErr):
+		return parameters, &BodyError{
+			Error: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value),
+			Field: typeErr.Field,
+		}
+	case errors.As(err, &syntaxErr):
+		return parameters, &BodyError{Error: fmt.Sprintf("invalid JSON at offset %d: %s", syntaxErr.Offset, syntaxErr)}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return parameters, &BodyError{Error: "unknown field", Field: field}
+	default:
+		return parameters, &BodyError{Error: err.Error()}
+	}
+}
+
+func badRequest(bodyErr *BodyError) (events.APIGatewayProxyResponse, error) {
+	body, err := json.Marshal(bodyErr)
+	if err != nil {
+		return serverError(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode:      http.StatusBadRequest,
+		Headers:         map[string]string{"Content-Type": "application/json", "Access-Control-Allow-Origin": "*"},
+		IsBase64Encoded: false,
+		Body:            string(body),
+	}, nil
+}
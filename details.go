@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+var detailsCache = newLayeredCache("details", time.Hour)
+
+var noDetailsError = &BodyError{Error: "details are not available for this place", Field: "placeId"}
+
+// detailsUnsupportedError is returned for places whose provider has no
+// details lookup, such as OSM.
+type detailsUnsupportedError struct {
+	placeID string
+}
+
+func (e *detailsUnsupportedError) Error() string {
+	return fmt.Sprintf("place details unsupported for %s", e.placeID)
+}
+
+func isDetailsUnsupported(err error) bool {
+	var unsupported *detailsUnsupportedError
+	return errors.As(err, &unsupported)
+}
+
+// logDetailsError logs a failed details lookup. Places without details are
+// expected and not logged.
+func logDetailsError(err error) {
+	if !isDetailsUnsupported(err) {
+		errorLogger.Println(err)
+	}
+}
+
+// detailsFailure answers a verb whose place details lookup failed.
+func detailsFailure(err error) (events.APIGatewayProxyResponse, error) {
+	if isDetailsUnsupported(err) {
+		return badRequest(noDetailsError)
+	}
+	return serverError(err)
+}
+
+// respondPlaceDetails fetches a place from its provider through
+// detailsCache, keyed by the place and the fields asked for.
+func respondPlaceDetails(placeID string, fields ...maps.PlaceDetailsFieldMask) (maps.PlaceDetailsResult, error) {
+	ctx := context.Background()
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = string(field)
+	}
+	key := cacheKey("details", placeID, strings.Join(names, ","))
+	var place maps.PlaceDetailsResult
+	if detailsCache.get(ctx, key, &place) {
+		return place, nil
+	}
+	place, err := fetchPlaceDetails(ctx, placeID, fields)
+	if err != nil {
+		return maps.PlaceDetailsResult{}, err
+	}
+	detailsCache.set(ctx, key, place)
+	return place, nil
+}
+
+func fetchPlaceDetails(ctx context.Context, placeID string, fields []maps.PlaceDetailsFieldMask) (maps.PlaceDetailsResult, error) {
+	if strings.HasPrefix(placeID, osmIDPrefix) {
+		return maps.PlaceDetailsResult{}, &detailsUnsupportedError{placeID: placeID}
+	}
+	if strings.HasPrefix(placeID, foursquareIDPrefix) {
+		return foursquareDetails(ctx, placeID)
+	}
+	if strings.HasPrefix(placeID, fixtureIDPrefix) {
+		return fixtureDetails(placeID)
+	}
+	var client *maps.Client
+	var err error
+	client, err = googleClient()
+	if err != nil {
+		return maps.PlaceDetailsResult{}, err
+	}
+	r := &maps.PlaceDetailsRequest{
+		PlaceID: placeID,
+		Fields:  fields,
+	}
+	return client.PlaceDetails(ctx, r)
+}
+
+var placeSummaryCache = newTTLCache(24 * time.Hour)
+
+type PlaceSummary struct {
+	PlaceID           string      `json:"placeId"`
+	Name              string      `json:"name"`
+	Vicinity          string      `json:"vicinity,omitempty"`
+	Location          maps.LatLng `json:"location"`
+	Rating            float32     `json:"rating,omitempty"`
+	UserRatingsTotal  int         `json:"userRatingsTotal,omitempty"`
+	PriceLevel        int         `json:"priceLevel,omitempty"`
+	PhotoRef          string      `json:"photoRef,omitempty"`
+	BusinessStatus    string      `json:"businessStatus,omitempty"`
+	RefreshedFrom     string      `json:"refreshedFrom,omitempty"`
+	PermanentlyClosed bool        `json:"permanentlyClosed,omitempty"`
+	TemporarilyClosed bool        `json:"temporarilyClosed,omitempty"`
+	// NotFound is set when Google no longer knows the ID, even after an ID
+	// refresh; the place is gone and should be dropped wherever it is saved.
+	NotFound bool `json:"notFound,omitempty"`
+}
+
+var summaryFields = []maps.PlaceDetailsFieldMask{
+	maps.PlaceDetailsFieldMaskPlaceID,
+	maps.PlaceDetailsFieldMaskName,
+	maps.PlaceDetailsFieldMaskVicinity,
+	maps.PlaceDetailsFieldMaskGeometry,
+	maps.PlaceDetailsFieldMaskRatings,
+	maps.PlaceDetailsFieldMaskUserRatingsTotal,
+	maps.PlaceDetailsFieldMaskPriceLevel,
+	maps.PlaceDetailsFieldMaskPhotos,
+	maps.PlaceDetailsFieldMaskBusinessStatus,
+}
+
+func isNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "NOT_FOUND")
+}
+
+// placeSummary returns the card-sized view of a place used to hydrate lists
+// of place IDs, cached per container for a day. Details requests for an
+// outdated ID answer with the place's current one; the summary then carries
+// the new ID and RefreshedFrom names the old. When Google answers NOT_FOUND
+// instead, the ID is refreshed with a place_id-only request, and a place that
+// is still not found comes back marked NotFound.
+func placeSummary(placeID string) (*PlaceSummary, error) {
+	if cached, ok := placeSummaryCache.get(placeID); ok {
+		return cached.(*PlaceSummary), nil
+	}
+	place, err := respondPlaceDetails(placeID, summaryFields...)
+	if isNotFound(err) {
+		return refreshedSummary(placeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	summary := &PlaceSummary{
+		PlaceID:           placeID,
+		Name:              place.Name,
+		Vicinity:          place.Vicinity,
+		Location:          place.Geometry.Location,
+		Rating:            place.Rating,
+		UserRatingsTotal:  place.UserRatingsTotal,
+		PriceLevel:        place.PriceLevel,
+		BusinessStatus:    place.BusinessStatus,
+		PermanentlyClosed: place.BusinessStatus == businessClosedPermanently,
+		TemporarilyClosed: place.BusinessStatus == businessClosedTemporarily,
+	}
+	if place.PlaceID != "" && place.PlaceID != placeID {
+		summary.PlaceID = place.PlaceID
+		summary.RefreshedFrom = placeID
+	}
+	if len(place.Photos) > 0 {
+		summary.PhotoRef = place.Photos[0].PhotoReference
+	}
+	placeSummaryCache.set(placeID, summary)
+	return summary, nil
+}
+
+// refreshedSummary asks Google for placeID's current ID, which is free, and
+// summarizes the place under it.
+func refreshedSummary(placeID string) (*PlaceSummary, error) {
+	current, err := respondPlaceDetails(placeID, maps.PlaceDetailsFieldMaskPlaceID)
+	if isNotFound(err) {
+		summary := &PlaceSummary{PlaceID: placeID, NotFound: true}
+		placeSummaryCache.set(placeID, summary)
+		return summary, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if current.PlaceID == "" || current.PlaceID == placeID {
+		return nil, fmt.Errorf("place details: %s not found but not refreshed", placeID)
+	}
+	found, err := placeSummary(current.PlaceID)
+	if err != nil {
+		return nil, err
+	}
+	summary := *found
+	summary.RefreshedFrom = placeID
+	placeSummaryCache.set(placeID, &summary)
+	return &summary, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/bedrockruntime"
+	"googlemaps.github.io/maps"
+)
+
+const (
+	digestBedrock    = "bedrock"
+	digestMaxReviews = 5
+	digestMaxTokens  = 400
+	digestPrompt     = `Summarize these restaurant reviews for %s. Reply with only a JSON object: {"digest": "<exactly two sentences>", "pros": ["<up to 3 short tags>"], "cons": ["<up to 3 short tags>"]}.
+
+%s`
+)
+
+var digestCache = newTTLCache(7 * 24 * time.Hour)
+
+type ReviewDigest struct {
+	Digest string   `json:"digest"`
+	Pros   []string `json:"pros"`
+	Cons   []string `json:"cons"`
+}
+
+// digestProvider turns a prompt into model text. Only the transport differs
+// between providers; prompting and parsing are shared.
+type digestProvider interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// bedrockDigest calls a Bedrock model that speaks the messages API.
+type bedrockDigest struct {
+	client  *bedrockruntime.BedrockRuntime
+	modelID string
+}
+
+func (b bedrockDigest) Complete(ctx context.Context, prompt string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"anthropic_version": "bedrock-2023-05-31",
+		"max_tokens":        digestMaxTokens,
+		"messages":          []map[string]string{{"role": "user", "content": prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+	out, err := b.client.InvokeModelWithContext(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(b.modelID),
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	err = json.Unmarshal(out.Body, &resp)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Content) == 0 {
+		return "", errors.New("bedrock: empty response")
+	}
+	return resp.Content[0].Text, nil
+}
+
+var digester struct {
+	once sync.Once
+	digestProvider
+}
+
+func configuredDigester() digestProvider {
+	digester.once.Do(func() {
+		if cfg.SummaryProvider == digestBedrock {
+			digester.digestProvider = bedrockDigest{client: bedrockruntime.New(awsSession), modelID: cfg.SummaryModelID}
+		}
+	})
+	return digester.digestProvider
+}
+
+// reviewDigest summarizes a place's top reviews, cached per place for a
+// week. It returns nil when no provider is configured or there is nothing
+// to summarize.
+func reviewDigest(ctx context.Context, place maps.PlaceDetailsResult) (*ReviewDigest, error) {
+	provider := configuredDigester()
+	if provider == nil || len(place.Reviews) == 0 {
+		return nil, nil
+	}
+	if cached, ok := digestCache.get(place.PlaceID); ok {
+		return cached.(*ReviewDigest), nil
+	}
+	var reviews []string
+	for i, review := range place.Reviews {
+		if i == digestMaxReviews {
+			break
+		}
+		reviews = append(reviews, fmt.Sprintf("- (%d/5) %s", review.Rating, review.Text))
+	}
+	text, err := provider.Complete(ctx, fmt.Sprintf(digestPrompt, place.Name, strings.Join(reviews, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("review digest: no JSON in model output")
+	}
+	digest := &ReviewDigest{}
+	err = json.Unmarshal([]byte(text[start:end+1]), digest)
+	if err != nil {
+		return nil, err
+	}
+	digest.Digest = sanitizeText(digest.Digest)
+	digest.Pros = sanitizePoints(digest.Pros)
+	digest.Cons = sanitizePoints(digest.Cons)
+	digestCache.set(place.PlaceID, digest)
+	return digest, nil
+}
+
+// sanitizePoints cleans each pro or con like any other review text, dropping
+// the ones left empty.
+func sanitizePoints(points []string) []string {
+	var kept []string
+	for _, point := range points {
+		if point = sanitizeText(point); point != "" {
+			kept = append(kept, point)
+		}
+	}
+	return kept
+}
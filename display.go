@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	displaySeparator   = " · "
+	displayConcurrency = 5
+)
+
+// DisplayStrings are ready-to-render labels for a result, so clients don't
+// each re-implement price, cuisine, distance and hours formatting.
+type DisplayStrings struct {
+	Summary  string `json:"summary"`
+	Price    string `json:"price,omitempty"`
+	Cuisine  string `json:"cuisine,omitempty"`
+	Distance string `json:"distance,omitempty"`
+	Hours    string `json:"hours,omitempty"`
+}
+
+type displayLocale struct {
+	Tag               string
+	Currency          string
+	Miles             bool
+	Decimal           string
+	OpenNow           string
+	Closed            string
+	TemporarilyClosed string
+	// OpenUntil takes the closing time, in 12-hour form when Clock12 is set.
+	OpenUntil string
+	Clock12   bool
+	Cuisines  map[string]string
+}
+
+var displayLocales = map[string]displayLocale{
+	"en": {Tag: "en-US", Currency: "$", Miles: true, Decimal: ".", OpenNow: "Open now", Closed: "Closed", TemporarilyClosed: "Temporarily closed", OpenUntil: "Open until %s", Clock12: true},
+	"es": {Tag: "es", Currency: "€", Decimal: ",", OpenNow: "Abierto ahora", Closed: "Cerrado", TemporarilyClosed: "Cerrado temporalmente", OpenUntil: "Abierto hasta las %s", Cuisines: map[string]string{
+		"italian": "Italiana", "mexican": "Mexicana", "japanese": "Japonesa", "chinese": "China", "indian": "India",
+		"french": "Francesa", "american": "Americana", "thai": "Tailandesa", "pizza": "Pizza", "sushi": "Sushi",
+		"burger": "Hamburguesas", "breakfast": "Desayunos", "vegetarian": "Vegetariana",
+	}},
+	"fr": {Tag: "fr", Currency: "€", Decimal: ",", OpenNow: "Ouvert", Closed: "Fermé", TemporarilyClosed: "Fermé temporairement", OpenUntil: "Ouvert jusqu'à %s", Cuisines: map[string]string{
+		"italian": "Italien", "mexican": "Mexicain", "japanese": "Japonais", "chinese": "Chinois", "indian": "Indien",
+		"french": "Français", "american": "Américain", "thai": "Thaï", "pizza": "Pizza", "sushi": "Sushi",
+		"burger": "Burgers", "breakfast": "Petit-déjeuner", "vegetarian": "Végétarien",
+	}},
+	"de": {Tag: "de", Currency: "€", Decimal: ",", OpenNow: "Jetzt geöffnet", Closed: "Geschlossen", TemporarilyClosed: "Vorübergehend geschlossen", OpenUntil: "Geöffnet bis %s", Cuisines: map[string]string{
+		"italian": "Italienisch", "mexican": "Mexikanisch", "japanese": "Japanisch", "chinese": "Chinesisch", "indian": "Indisch",
+		"french": "Französisch", "american": "Amerikanisch", "thai": "Thailändisch", "pizza": "Pizza", "sushi": "Sushi",
+		"burger": "Burger", "breakfast": "Frühstück", "vegetarian": "Vegetarisch",
+	}},
+}
+
+// regionCurrencies overrides the language default where the region decides,
+// e.g. en-GB or es-MX. It is only the fallback for places whose own country
+// isn't known.
+var regionCurrencies = map[string]string{
+	"GB": "£", "IE": "€", "MX": "$", "AR": "$", "CO": "$", "CH": "CHF", "CA": "$", "AU": "$",
+}
+
+// currencySymbols renders the bill regions' ISO currencies.
+var currencySymbols = map[string]string{
+	"USD": "$", "CAD": "$", "AUD": "$", "MXN": "$", "GBP": "£", "EUR": "€", "JPY": "¥", "CHF": "CHF",
+}
+
+// genericTypes never name a cuisine.
+var genericTypes = map[string]bool{
+	"restaurant": true, "food": true, "point_of_interest": true, "establishment": true,
+	"meal_takeaway": true, "meal_delivery": true, "store": true, "bar": true, "cafe": true,
+	osmWheelchairType: true, osmDriveThroughType: true,
+}
+
+// localeFor picks the best supported locale from an Accept-Language header,
+// honouring q-values, and falls back to en-US.
+func localeFor(acceptLanguage string) displayLocale {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if tag != "" {
+			candidates = append(candidates, candidate{tag, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		lang, region, _ := strings.Cut(c.tag, "-")
+		locale, ok := displayLocales[strings.ToLower(lang)]
+		if !ok {
+			continue
+		}
+		region = strings.ToUpper(region)
+		if region != "" {
+			locale.Tag = strings.ToLower(lang) + "-" + region
+			if currency, ok := regionCurrencies[region]; ok {
+				locale.Currency = currency
+			}
+			locale.Miles = region == "US" || region == "GB" || region == "LR" || region == "MM"
+		}
+		return locale
+	}
+	return displayLocales["en"]
+}
+
+// currencyAt is the currency symbol where point is, from the bill regions,
+// falling back to the locale's.
+func currencyAt(ctx context.Context, point maps.LatLng, locale displayLocale) string {
+	_, region, ok := billRegionFor(regionAt(ctx, point))
+	if !ok {
+		return locale.Currency
+	}
+	if symbol, ok := currencySymbols[region.Currency]; ok {
+		return symbol
+	}
+	return region.Currency
+}
+
+// localizeResults fills Display on every result. Prices are in the place's
+// own currency, and distances are only given when the search origin is
+// known.
+func localizeResults(ctx context.Context, biteArray *BiteResponse, acceptLanguage string, origin *maps.LatLng) {
+	locale := localeFor(acceptLanguage)
+	now := time.Now()
+	sem := make(chan struct{}, displayConcurrency)
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		wg.Add(1)
+		go func(result *BiteResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result.Display = displayStrings(ctx, *result, locale, origin, now)
+		}(&biteArray.Results[i])
+	}
+	wg.Wait()
+	if biteArray.Meta == nil {
+		biteArray.Meta = &ResponseMeta{}
+	}
+	biteArray.Meta.Locale = locale.Tag
+}
+
+func displayStrings(ctx context.Context, result BiteResult, locale displayLocale, origin *maps.LatLng, now time.Time) *DisplayStrings {
+	d := &DisplayStrings{}
+	if result.PriceLevel > 0 {
+		d.Price = strings.Repeat(currencyAt(ctx, result.Geometry.Location, locale), result.PriceLevel)
+	}
+	if len(result.Cuisines) > 0 {
+		d.Cuisine = localizedCuisine(result.Cuisines[0], locale)
+	} else {
+		d.Cuisine = cuisineLabel(result.Types, locale)
+	}
+	if origin != nil {
+		d.Distance = formatDistance(distanceMeters(*origin, result.Geometry.Location), locale)
+	}
+	if result.TemporarilyClosed {
+		d.Hours = locale.TemporarilyClosed
+	} else if result.OpeningHours != nil && result.OpeningHours.OpenNow != nil {
+		d.Hours = locale.Closed
+		if *result.OpeningHours.OpenNow {
+			d.Hours = openUntil(ctx, result.PlaceID, locale, now)
+		}
+	}
+	var parts []string
+	for _, part := range []string{d.Price, d.Cuisine, d.Distance, d.Hours} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	d.Summary = strings.Join(parts, displaySeparator)
+	return d
+}
+
+// openUntil is "Open until 10 PM" in the place's own time, or just "Open
+// now" when its closing time isn't known.
+func openUntil(ctx context.Context, placeID string, locale displayLocale, now time.Time) string {
+	hours := hoursFor(ctx, placeID)
+	if hours == nil || hours.alwaysOpen {
+		return locale.OpenNow
+	}
+	closes, ok := hours.closesAt(now)
+	if !ok {
+		return locale.OpenNow
+	}
+	return fmt.Sprintf(locale.OpenUntil, formatClock(closes, locale))
+}
+
+func formatClock(t time.Time, locale displayLocale) string {
+	if !locale.Clock12 {
+		return t.Format("15:04")
+	}
+	if t.Minute() == 0 {
+		return t.Format("3 PM")
+	}
+	return t.Format("3:04 PM")
+}
+
+func localizedCuisine(id string, locale displayLocale) string {
+	if label, ok := locale.Cuisines[id]; ok {
+		return label
+	}
+	return cuisineLabelFor(id)
+}
+
+func cuisineLabel(types []string, locale displayLocale) string {
+	for _, t := range types {
+		if genericTypes[t] {
+			continue
+		}
+		cuisine := strings.TrimSuffix(t, "_restaurant")
+		if label, ok := locale.Cuisines[cuisine]; ok {
+			return label
+		}
+		words := strings.Fields(strings.ReplaceAll(cuisine, "_", " "))
+		for i, w := range words {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+		return strings.Join(words, " ")
+	}
+	return ""
+}
+
+func formatDistance(meters float64, locale displayLocale) string {
+	if locale.Miles {
+		return formatDecimal(meters/1609.344, locale) + " mi"
+	}
+	if meters < 1000 {
+		return strconv.Itoa(int(math.Round(meters/10)*10)) + " m"
+	}
+	return formatDecimal(meters/1000, locale) + " km"
+}
+
+func formatDecimal(v float64, locale displayLocale) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', 1, 64), ".", locale.Decimal, 1)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	osmDriveThroughType    = "drive_through"
+	communityDriveThrough  = "drive-through"
+	driveThroughConcurrent = 5
+)
+
+// Neither Places API has a drive-through attribute, so it is inferred from
+// the name, OSM's drive_through tag, community tags and review mentions.
+var driveThroughPhrases = []string{"drive-thru", "drive thru", "drivethru", "drive-through", "drive through"}
+
+var reviewsCache = newTTLCache(24 * time.Hour)
+
+var reviewFields = []maps.PlaceDetailsFieldMask{maps.PlaceDetailsFieldMaskPlaceID, maps.PlaceDetailsFieldMaskReviews}
+
+// placeReviews returns a Google place's reviews, cached for the review
+// mining heuristics. Other providers' places have none.
+func placeReviews(placeID string) ([]maps.PlaceReview, error) {
+	if strings.HasPrefix(placeID, foursquareIDPrefix) {
+		return nil, nil
+	}
+	if cached, ok := reviewsCache.get(placeID); ok {
+		return cached.([]maps.PlaceReview), nil
+	}
+	place, err := respondPlaceDetails(placeID, reviewFields...)
+	if isDetailsUnsupported(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	reviewsCache.set(placeID, place.Reviews)
+	return place.Reviews, nil
+}
+
+// hasDriveThrough checks the free signals first and only fetches reviews
+// when none of them settle it.
+func hasDriveThrough(result BiteResult) bool {
+	if containsAny(strings.ToLower(result.Name), driveThroughPhrases) {
+		return true
+	}
+	if contains(result.Types, osmDriveThroughType) || contains(result.CommunityTags, communityDriveThrough) {
+		return true
+	}
+	reviews, err := placeReviews(result.PlaceID)
+	if err != nil {
+		errorLogger.Println(err)
+		return false
+	}
+	for _, review := range reviews {
+		if containsAny(strings.ToLower(review.Text), driveThroughPhrases) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterDriveThrough keeps results with signs of a drive-through and flags
+// them.
+func filterDriveThrough(ctx context.Context, biteArray *BiteResponse) {
+	keep := make([]bool, len(biteArray.Results))
+	sem := make(chan struct{}, driveThroughConcurrent)
+	var wg sync.WaitGroup
+	for i, result := range biteArray.Results {
+		wg.Add(1)
+		go func(i int, result BiteResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() == nil {
+				keep[i] = hasDriveThrough(result)
+			}
+		}(i, result)
+	}
+	wg.Wait()
+	kept := make([]BiteResult, 0, len(biteArray.Results))
+	for i, result := range biteArray.Results {
+		if keep[i] {
+			result.DriveThrough = true
+			kept = append(kept, result)
+		}
+	}
+	biteArray.Results = kept
+}
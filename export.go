@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var csvHeader = []string{"name", "address", "street", "city", "region", "postal_code", "country", "rating", "ratings_total", "price_level", "latitude", "longitude", "place_id", "maps_url"}
+
+// handleExport writes the results a create or nextpage request with the same
+// parameters would return as CSV, so the file matches what the user sees.
+func handleExport(ctx context.Context, parameters BiteBody, tenant *tenantProfile) (events.APIGatewayProxyResponse, error) {
+	var biteArray BiteResponse
+	var err error
+	if parameters.PageToken != "" {
+		if !validPageSize(parameters.PageSize) {
+			return badRequest(pageSizeError)
+		}
+		biteArray, _, err = nextPage(ctx, parameters, tenant)
+	} else {
+		if bodyErr := validateCreate(parameters); bodyErr != nil {
+			return badRequest(bodyErr)
+		}
+		biteArray, err = createPage(ctx, parameters, tenant)
+	}
+	if err != nil {
+		return serverError(err)
+	}
+	body, err := resultsCSV(biteArray.Results)
+	if err != nil {
+		return serverError(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":                  "text/csv; charset=utf-8",
+			"Content-Disposition":           `attachment; filename="bites.csv"`,
+			"Access-Control-Allow-Origin":   "*",
+			"Access-Control-Expose-Headers": "Content-Disposition",
+		},
+		IsBase64Encoded: false,
+		Body:            body,
+	}, nil
+}
+
+// csvText keeps provider and user text from being read as a spreadsheet
+// formula by prefixing it with a quote.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func resultsCSV(results []BiteResult) (string, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	err := w.Write(csvHeader)
+	if err != nil {
+		return "", err
+	}
+	addresses := resultAddresses(results)
+	for i, result := range results {
+		address := addresses[i]
+		if address == nil {
+			address = &StructuredAddress{}
+		}
+		err = w.Write([]string{
+			csvText(result.Name),
+			csvText(result.Vicinity),
+			csvText(address.Street),
+			csvText(address.City),
+			csvText(address.Region),
+			csvText(address.PostalCode),
+			csvText(address.CountryCode),
+			strconv.FormatFloat(float64(result.Rating), 'f', 1, 32),
+			strconv.Itoa(result.UserRatingsTotal),
+			strconv.Itoa(result.PriceLevel),
+			strconv.FormatFloat(result.Geometry.Location.Lat, 'f', 6, 64),
+			strconv.FormatFloat(result.Geometry.Location.Lng, 'f', 6, 64),
+			csvText(result.PlaceID),
+			csvText(placeMapsURL(result.Name, result.PlaceID)),
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+func placeMapsURL(name, placeID string) string {
+	return fmt.Sprintf("https://www.google.com/maps/search/?api=1&query=%s&query_place_id=%s", url.QueryEscape(name), url.QueryEscape(placeID))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const faultAny = "*"
+
+// faultStages are the only stages FAULT_INJECTION is honoured in. STAGE
+// defaults to "unset", so a deploy that doesn't name its stage never injects.
+var faultStages = map[string]bool{"dev": true, "test": true, "staging": true}
+
+var errInjectedFault = errors.New("fault injection: provider unavailable")
+
+type faultConfig struct {
+	LatencyMs     int     `json:"latencyMs"`
+	ErrorRate     float64 `json:"errorRate"`
+	MalformedRate float64 `json:"malformedRate"`
+}
+
+// faults holds fault injection settings from FAULT_INJECTION, keyed by verb,
+// "verb:provider", "*:provider" or "*", e.g.
+// {"create:google": {"latencyMs": 2000, "errorRate": 0.2}, "*": {"malformedRate": 0.05}}.
+// They apply to provider searches, and are parsed on first use rather than
+// at cold start.
+var faults struct {
+	once   sync.Once
+	config map[string]faultConfig
+}
+
+type verbContextKey struct{}
+
+// withVerb records the verb being served, so provider calls deep in it can
+// look up their faults.
+func withVerb(ctx context.Context, verb string) context.Context {
+	return context.WithValue(ctx, verbContextKey{}, verb)
+}
+
+func verbFrom(ctx context.Context) string {
+	verb, _ := ctx.Value(verbContextKey{}).(string)
+	return verb
+}
+
+func loadFaults(raw, stage string) map[string]faultConfig {
+	if raw == "" {
+		return nil
+	}
+	if !faultStages[stage] {
+		log.Printf("FAULT_INJECTION ignored in stage %q", stage)
+		return nil
+	}
+	var config map[string]faultConfig
+	if json.Unmarshal([]byte(raw), &config) != nil {
+		return nil
+	}
+	log.Printf("fault injection enabled: %s", raw)
+	return config
+}
+
+// faultFor picks the most specific setting for a provider call in verb.
+func faultFor(verb, provider string) (faultConfig, bool) {
+	faults.once.Do(func() {
+		faults.config = loadFaults(cfg.FaultInjection, cfg.Stage)
+	})
+	for _, key := range []string{verb + ":" + provider, verb, faultAny + ":" + provider, faultAny} {
+		if config, ok := faults.config[key]; ok {
+			return config, true
+		}
+	}
+	return faultConfig{}, false
+}
+
+// searchWithFaults is p.Search with the configured latency, error rate and
+// malformed-payload rate. A malformed payload fails the way a truncated
+// provider body would, with the decoder's error.
+func searchWithFaults(ctx context.Context, p placesProvider, params searchParams) (maps.PlacesSearchResponse, error) {
+	config, ok := faultFor(verbFrom(ctx), p.Name())
+	if !ok {
+		return p.Search(ctx, params)
+	}
+	if config.LatencyMs > 0 {
+		select {
+		case <-time.After(time.Duration(config.LatencyMs) * time.Millisecond):
+		case <-ctx.Done():
+			return maps.PlacesSearchResponse{}, ctx.Err()
+		}
+	}
+	if rand.Float64() < config.ErrorRate {
+		log.Printf("fault injection: failing %s", p.Name())
+		return maps.PlacesSearchResponse{}, errInjectedFault
+	}
+	resp, err := p.Search(ctx, params)
+	if err == nil && rand.Float64() < config.MalformedRate {
+		log.Printf("fault injection: truncating %s payload", p.Name())
+		raw, err := json.Marshal(resp)
+		if err != nil {
+			return maps.PlacesSearchResponse{}, err
+		}
+		var truncated maps.PlacesSearchResponse
+		err = json.Unmarshal(raw[:len(raw)/2], &truncated)
+		return maps.PlacesSearchResponse{}, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+	return resp, err
+}
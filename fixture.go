@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	providerFixture    = "fixture"
+	fixturePhotoPrefix = "fixture:"
+	fixtureIDPrefix    = "fixture:"
+)
+
+//go:embed testdata/fixture
+var fixtureFS embed.FS
+
+// fixtureCenter anchors the dataset for details lookups, which have no search
+// location to be placed around.
+var fixtureCenter = maps.LatLng{Lat: 39.7527, Lng: -104.9995}
+
+var fixtureCapabilities = providerCapabilities{
+	filterKeyword:  filterEmulated,
+	filterMealType: filterEmulated,
+	filterOpenNow:  filterEmulated,
+	filterPrice:    filterEmulated,
+	filterRadius:   filterEmulated,
+}
+
+type fixturePlace struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	NorthMeters      float64  `json:"northMeters"`
+	EastMeters       float64  `json:"eastMeters"`
+	Vicinity         string   `json:"vicinity"`
+	Types            []string `json:"types"`
+	Rating           float32  `json:"rating"`
+	UserRatingsTotal int      `json:"userRatingsTotal"`
+	PriceLevel       int      `json:"priceLevel"`
+	OpenNow          bool     `json:"openNow"`
+	Photo            string   `json:"photo"`
+	Phone            string   `json:"phone"`
+	Website          string   `json:"website"`
+}
+
+// fixtureProvider serves a bundled dataset so demos and end-to-end tests run
+// offline. Places are stored as offsets and laid out around whatever location
+// is searched, so results are identical everywhere.
+type fixtureProvider struct{}
+
+func init() {
+	registeredProviders[providerFixture] = fixtureProvider{}
+}
+
+func (fixtureProvider) Name() string {
+	return providerFixture
+}
+
+func (fixtureProvider) Capabilities() providerCapabilities {
+	return fixtureCapabilities
+}
+
+func (fixtureProvider) Search(ctx context.Context, params searchParams) (maps.PlacesSearchResponse, error) {
+	places, err := loadFixturePlaces()
+	if err != nil {
+		return maps.PlacesSearchResponse{}, err
+	}
+	origin := maps.LatLng{Lat: params.Lat, Lng: params.Long}
+	resp := maps.PlacesSearchResponse{HTMLAttributions: []string{"Bite fixture data"}}
+	for _, place := range places {
+		resp.Results = append(resp.Results, place.searchResult(origin))
+	}
+	sort.SliceStable(resp.Results, func(i, j int) bool {
+		return resp.Results[i].UserRatingsTotal > resp.Results[j].UserRatingsTotal
+	})
+	return resp, nil
+}
+
+func loadFixturePlaces() ([]fixturePlace, error) {
+	data, err := fixtureFS.ReadFile("testdata/fixture/places.json")
+	if err != nil {
+		return nil, err
+	}
+	var places []fixturePlace
+	err = json.Unmarshal(data, &places)
+	return places, err
+}
+
+func (place fixturePlace) location(origin maps.LatLng) maps.LatLng {
+	lat := origin.Lat + place.NorthMeters/metersPerDegree
+	long := origin.Lng + place.EastMeters/(metersPerDegree*math.Max(math.Cos(origin.Lat*math.Pi/180), 0.01))
+	return maps.LatLng{Lat: lat, Lng: long}
+}
+
+func (place fixturePlace) searchResult(origin maps.LatLng) maps.PlacesSearchResult {
+	openNow := place.OpenNow
+	return maps.PlacesSearchResult{
+		PlaceID:          place.ID,
+		Name:             place.Name,
+		Geometry:         maps.AddressGeometry{Location: place.location(origin)},
+		Vicinity:         place.Vicinity,
+		Types:            place.Types,
+		Rating:           place.Rating,
+		UserRatingsTotal: place.UserRatingsTotal,
+		PriceLevel:       place.PriceLevel,
+		OpeningHours:     &maps.OpeningHours{OpenNow: &openNow},
+		BusinessStatus:   "OPERATIONAL",
+		Photos: []maps.Photo{{
+			PhotoReference: fixturePhotoPrefix + place.Photo,
+			Width:          64,
+			Height:         48,
+		}},
+	}
+}
+
+func fixtureDetails(placeID string) (maps.PlaceDetailsResult, error) {
+	places, err := loadFixturePlaces()
+	if err != nil {
+		return maps.PlaceDetailsResult{}, err
+	}
+	for _, place := range places {
+		if place.ID != placeID {
+			continue
+		}
+		result := place.searchResult(fixtureCenter)
+		return maps.PlaceDetailsResult{
+			PlaceID:                  result.PlaceID,
+			Name:                     result.Name,
+			FormattedAddress:         place.Vicinity + ", Denver, CO, USA",
+			Vicinity:                 result.Vicinity,
+			Geometry:                 result.Geometry,
+			Types:                    result.Types,
+			Rating:                   result.Rating,
+			UserRatingsTotal:         result.UserRatingsTotal,
+			PriceLevel:               result.PriceLevel,
+			OpeningHours:             result.OpeningHours,
+			BusinessStatus:           result.BusinessStatus,
+			Photos:                   result.Photos,
+			InternationalPhoneNumber: place.Phone,
+			Website:                  place.Website,
+		}, nil
+	}
+	return maps.PlaceDetailsResult{}, fmt.Errorf("maps: NOT_FOUND - fixture place %s", placeID)
+}
+
+func fixturePhoto(ref string) (maps.PlacePhotoResponse, error) {
+	name := strings.TrimPrefix(ref, fixturePhotoPrefix)
+	if strings.Contains(name, "/") {
+		return maps.PlacePhotoResponse{}, fmt.Errorf("fixture photo: bad reference %q", ref)
+	}
+	f, err := fixtureFS.Open("testdata/fixture/photos/" + name)
+	if err != nil {
+		return maps.PlacePhotoResponse{}, err
+	}
+	return maps.PlacePhotoResponse{ContentType: "image/jpeg", Data: f}, nil
+}
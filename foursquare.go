@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	providerFoursquare     = "foursquare"
+	foursquareBaseURL      = "https://api.foursquare.com/v3/places"
+	foursquareFoodCategory = "13065"
+	foursquareFields       = "fsq_id,name,geocodes,location,categories,rating,stats,price,photos,tel,website"
+	foursquareIDPrefix     = "fsq:"
+	foursquarePhotoPrefix  = "fsq:"
+	foursquarePhotoHost    = ".4sqi.net"
+	foursquareAttribution  = "Powered by Foursquare"
+)
+
+// foursquareCuisines maps Foursquare category names that don't follow the
+// "<Cuisine> Restaurant" pattern onto our cuisine names.
+var foursquareCuisines = map[string]string{
+	"Pizza Place":    "pizza",
+	"Burger Joint":   "burger",
+	"Taco Place":     "mexican",
+	"Sushi Bar":      "sushi",
+	"Noodle House":   "noodles",
+	"Steakhouse":     "steak",
+	"BBQ Joint":      "bbq",
+	"Sandwich Spot":  "sandwich",
+	"Diner":          "american",
+	"Ramen Shop":     "ramen",
+	"Fried Chicken":  "chicken",
+	"Salad Place":    "salad",
+	"Breakfast Spot": "breakfast",
+	"Bistro":         "french",
+	"Gastropub":      "pub",
+	"Dim Sum Spot":   "chinese",
+	"Falafel Spot":   "middle_eastern",
+	"Poke Place":     "hawaiian",
+}
+
+type foursquareProvider struct{}
+
+type foursquarePlace struct {
+	ID       string `json:"fsq_id"`
+	Name     string `json:"name"`
+	Geocodes struct {
+		Main struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"main"`
+	} `json:"geocodes"`
+	Location struct {
+		Address          string `json:"address"`
+		Locality         string `json:"locality"`
+		FormattedAddress string `json:"formatted_address"`
+	} `json:"location"`
+	Categories []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"categories"`
+	Rating float32 `json:"rating"`
+	Stats  struct {
+		TotalRatings int `json:"total_ratings"`
+	} `json:"stats"`
+	Price  int `json:"price"`
+	Photos []struct {
+		Prefix string `json:"prefix"`
+		Suffix string `json:"suffix"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	} `json:"photos"`
+	Tel     string `json:"tel"`
+	Website string `json:"website"`
+}
+
+func init() {
+	registeredProviders[providerFoursquare] = foursquareProvider{}
+}
+
+func (foursquareProvider) Name() string {
+	return providerFoursquare
+}
+
+func (foursquareProvider) Capabilities() providerCapabilities {
+	return foursquareCapabilities
+}
+
+func (foursquareProvider) Search(ctx context.Context, params searchParams) (maps.PlacesSearchResponse, error) {
+	mealType, _ := mealTypeFor(params.MealType)
+	query := url.Values{
+		"ll":         {fmt.Sprintf("%f,%f", params.Lat, params.Long)},
+		"categories": {mealType.FoursquareCategory},
+		"limit":      {"50"},
+		"fields":     {foursquareFields},
+	}
+	if !params.IgnoreOpenNow {
+		query.Set("open_now", "true")
+	}
+	if params.Radius > 0 {
+		query.Set("radius", fmt.Sprint(params.Radius))
+	}
+	if params.MinPrice > 0 {
+		query.Set("min_price", fmt.Sprint(params.MinPrice))
+	}
+	if params.MaxPrice > 0 && params.MaxPrice < 5 {
+		query.Set("max_price", fmt.Sprint(params.MaxPrice))
+	}
+	if params.Keyword != "" {
+		query.Set("query", params.Keyword)
+	}
+	var body struct {
+		Results []foursquarePlace `json:"results"`
+	}
+	err := foursquareGet(ctx, foursquareBaseURL+"/search?"+query.Encode(), &body)
+	if err != nil {
+		return maps.PlacesSearchResponse{}, err
+	}
+	resp := maps.PlacesSearchResponse{HTMLAttributions: []string{foursquareAttribution}}
+	for _, place := range body.Results {
+		resp.Results = append(resp.Results, place.searchResult())
+	}
+	return resp, nil
+}
+
+func foursquareDetails(ctx context.Context, placeID string) (maps.PlaceDetailsResult, error) {
+	id := strings.TrimPrefix(placeID, foursquareIDPrefix)
+	var place foursquarePlace
+	err := foursquareGet(ctx, foursquareBaseURL+"/"+url.PathEscape(id)+"?fields="+foursquareFields, &place)
+	if err != nil {
+		return maps.PlaceDetailsResult{}, err
+	}
+	result := place.searchResult()
+	return maps.PlaceDetailsResult{
+		PlaceID:                  result.PlaceID,
+		Name:                     result.Name,
+		FormattedAddress:         place.Location.FormattedAddress,
+		Vicinity:                 result.Vicinity,
+		Geometry:                 result.Geometry,
+		Types:                    result.Types,
+		Rating:                   result.Rating * ratingScales[providerGoogle] / ratingScales[providerFoursquare],
+		UserRatingsTotal:         result.UserRatingsTotal,
+		PriceLevel:               result.PriceLevel,
+		Photos:                   result.Photos,
+		InternationalPhoneNumber: place.Tel,
+		Website:                  place.Website,
+		HTMLAttributions:         []string{foursquareAttribution},
+	}, nil
+}
+
+// foursquarePhoto fetches a photo reference produced by searchResult, which
+// carries the full CDN URL rather than a Google photo reference.
+func foursquarePhoto(ref string) (maps.PlacePhotoResponse, error) {
+	photoURL, err := url.Parse(strings.TrimPrefix(ref, foursquarePhotoPrefix))
+	if err != nil {
+		return maps.PlacePhotoResponse{}, err
+	}
+	if photoURL.Scheme != "https" || !strings.HasSuffix(photoURL.Hostname(), foursquarePhotoHost) {
+		return maps.PlacePhotoResponse{}, fmt.Errorf("foursquare photo: unexpected host %q", photoURL.Host)
+	}
+	resp, err := http.Get(photoURL.String())
+	if err != nil {
+		return maps.PlacePhotoResponse{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return maps.PlacePhotoResponse{}, fmt.Errorf("foursquare photo: %s", resp.Status)
+	}
+	return maps.PlacePhotoResponse{ContentType: resp.Header.Get("Content-Type"), Data: resp.Body}, nil
+}
+
+func foursquareGet(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", cfg.FoursquareAPIKey)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("foursquare: %s: %s", resp.Status, msg)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (place foursquarePlace) searchResult() maps.PlacesSearchResult {
+	result := maps.PlacesSearchResult{
+		PlaceID: foursquareIDPrefix + place.ID,
+		Name:    place.Name,
+		Geometry: maps.AddressGeometry{Location: maps.LatLng{
+			Lat: place.Geocodes.Main.Latitude,
+			Lng: place.Geocodes.Main.Longitude,
+		}},
+		Vicinity:         place.Location.Address,
+		Rating:           place.Rating,
+		UserRatingsTotal: place.Stats.TotalRatings,
+		PriceLevel:       place.Price,
+		Types:            []string{"restaurant"},
+	}
+	if place.Location.Locality != "" {
+		result.Vicinity = strings.TrimPrefix(result.Vicinity+", "+place.Location.Locality, ", ")
+	}
+	for _, category := range place.Categories {
+		if cuisine := foursquareCuisine(category.Name); cuisine != "" {
+			result.Types = append(result.Types, cuisine)
+		}
+	}
+	for _, photo := range place.Photos {
+		result.Photos = append(result.Photos, maps.Photo{
+			PhotoReference: foursquarePhotoPrefix + photo.Prefix + "original" + photo.Suffix,
+			Width:          photo.Width,
+			Height:         photo.Height,
+		})
+	}
+	return result
+}
+
+func foursquareCuisine(category string) string {
+	if cuisine, ok := foursquareCuisines[category]; ok {
+		return cuisine
+	}
+	if strings.HasSuffix(category, " Restaurant") {
+		name := strings.TrimSuffix(category, " Restaurant")
+		return strings.ReplaceAll(strings.ToLower(name), " ", "_")
+	}
+	return ""
+}
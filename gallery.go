@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+// maxGalleryPhotos is the most Place Details ever returns.
+const maxGalleryPhotos = 10
+
+type GalleryPhoto struct {
+	PhotoRef         string   `json:"photoRef"`
+	Width            int      `json:"width"`
+	Height           int      `json:"height"`
+	HTMLAttributions []string `json:"htmlAttributions"`
+}
+
+type Gallery struct {
+	PlaceID string         `json:"placeId"`
+	Photos  []GalleryPhoto `json:"photos"`
+}
+
+// handleGallery returns up to count photo references for a place's carousel.
+// Images themselves are still fetched one at a time through the photo verb.
+func handleGallery(placeID string, count int) (events.APIGatewayProxyResponse, error) {
+	if placeID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	if count <= 0 || count > maxGalleryPhotos {
+		count = maxGalleryPhotos
+	}
+	place, err := respondPlaceDetails(placeID, maps.PlaceDetailsFieldMaskPhotos)
+	if err != nil {
+		return detailsFailure(err)
+	}
+	gallery := Gallery{PlaceID: placeID, Photos: []GalleryPhoto{}}
+	for _, photo := range place.Photos {
+		if len(gallery.Photos) == count {
+			break
+		}
+		gallery.Photos = append(gallery.Photos, GalleryPhoto{
+			PhotoRef:         photo.PhotoReference,
+			Width:            photo.Width,
+			Height:           photo.Height,
+			HTMLAttributions: photo.HTMLAttributions,
+		})
+	}
+	return jsonSuccess(gallery)
+}
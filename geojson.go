@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const exportFormatGeoJSON = "geojson"
+
+// FeatureCollection carries the response's paging token and metadata as
+// foreign members, so GeoJSON clients can page like JSON ones.
+type FeatureCollection struct {
+	Type          string        `json:"type"`
+	Features      []Feature     `json:"features"`
+	NextPageToken string        `json:"nextPageToken,omitempty"`
+	Meta          *ResponseMeta `json:"meta,omitempty"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   PointGeometry          `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type PointGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+func toFeatureCollection(biteArray BiteResponse) FeatureCollection {
+	collection := FeatureCollection{
+		Type:          "FeatureCollection",
+		Features:      []Feature{},
+		NextPageToken: biteArray.NextPageToken,
+		Meta:          biteArray.Meta,
+	}
+	for _, result := range biteArray.Results {
+		properties := map[string]interface{}{
+			"placeId":          result.PlaceID,
+			"name":             result.Name,
+			"vicinity":         result.Vicinity,
+			"rating":           result.Rating,
+			"userRatingsTotal": result.UserRatingsTotal,
+			"priceLevel":       result.PriceLevel,
+			"types":            result.Types,
+			"sources":          result.Sources,
+		}
+		if len(result.Photos) > 0 {
+			properties["photoRef"] = result.Photos[0].PhotoReference
+		}
+		collection.Features = append(collection.Features, Feature{
+			Type: "Feature",
+			Geometry: PointGeometry{
+				Type:        "Point",
+				Coordinates: [2]float64{result.Geometry.Location.Lng, result.Geometry.Location.Lat},
+			},
+			Properties: properties,
+		})
+	}
+	return collection
+}
+
+func geoJSONSuccess(biteArray BiteResponse) (events.APIGatewayProxyResponse, error) {
+	body, err := json.Marshal(toFeatureCollection(biteArray))
+	if err != nil {
+		return serverError(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode:      http.StatusOK,
+		Headers:         map[string]string{"Content-Type": "application/geo+json", "Access-Control-Allow-Origin": "*"},
+		IsBase64Encoded: false,
+		Body:            string(body),
+	}, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+// WeeklySchedule is a place's opening hours in its own local time, with the
+// "right now" fields computed server-side.
+type WeeklySchedule struct {
+	TimeZone        string     `json:"timeZone"`
+	AlwaysOpen      bool       `json:"alwaysOpen,omitempty"`
+	Days            []DayHours `json:"days"`
+	IsOpenNow       bool       `json:"isOpenNow"`
+	ClosesInMinutes *int       `json:"closesInMinutes,omitempty"`
+	NextOpenAt      *time.Time `json:"nextOpenAt,omitempty"`
+
+	SpecialDays      []SpecialDay `json:"specialDays,omitempty"`
+	ExceptionalToday bool         `json:"exceptionalToday,omitempty"`
+	Warning          string       `json:"warning,omitempty"`
+}
+
+type DayHours struct {
+	Day       string          `json:"day"`
+	Intervals []HoursInterval `json:"intervals"`
+}
+
+// HoursInterval times are local "15:04". Overnight is set when Close falls on
+// the following day.
+type HoursInterval struct {
+	Open      string `json:"open"`
+	Close     string `json:"close"`
+	Overnight bool   `json:"overnight,omitempty"`
+}
+
+// weekInterval is a span in minutes since Sunday 00:00 local time. End may
+// run past the end of the week for Saturday-night periods.
+type weekInterval struct {
+	start, end int
+}
+
+// placeLocation is the place's time zone from its utc_offset, falling back to
+// UTC when Google didn't return one.
+func placeLocation(place maps.PlaceDetailsResult) *time.Location {
+	if place.UTCOffset == nil {
+		return time.UTC
+	}
+	offset := *place.UTCOffset
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return time.FixedZone(fmt.Sprintf("UTC%s%02d:%02d", sign, offset/60, offset%60), *place.UTCOffset*60)
+}
+
+// normalizeHours turns Google's period arrays into a WeeklySchedule evaluated
+// at now in loc. It returns nil when the place has no usable periods.
+func normalizeHours(hours *maps.OpeningHours, loc *time.Location, now time.Time) *WeeklySchedule {
+	if hours == nil || len(hours.Periods) == 0 {
+		return nil
+	}
+	schedule := &WeeklySchedule{TimeZone: loc.String(), Days: make([]DayHours, 7)}
+	for day := range schedule.Days {
+		schedule.Days[day] = DayHours{Day: time.Weekday(day).String(), Intervals: []HoursInterval{}}
+	}
+	// A single period that opens Sunday 00:00 with no close is Google's 24/7.
+	if len(hours.Periods) == 1 && hours.Periods[0].Close.Time == "" {
+		schedule.AlwaysOpen = true
+		schedule.IsOpenNow = true
+		return schedule
+	}
+	var intervals []weekInterval
+	for _, period := range hours.Periods {
+		iv, ok := periodInterval(period)
+		if !ok {
+			continue
+		}
+		intervals = append(intervals, iv)
+		day := iv.start / minutesPerDay
+		schedule.Days[day].Intervals = append(schedule.Days[day].Intervals, HoursInterval{
+			Open:      period.Open.Time[:2] + ":" + period.Open.Time[2:],
+			Close:     period.Close.Time[:2] + ":" + period.Close.Time[2:],
+			Overnight: (iv.end-1)/minutesPerDay != day,
+		})
+	}
+	if len(intervals) == 0 {
+		return nil
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start < intervals[j].start })
+
+	local := now.In(loc)
+	current := weekMinuteAt(local)
+	nextOpen := -1
+	for _, iv := range intervals {
+		if closesIn, ok := iv.remaining(current); ok {
+			schedule.IsOpenNow = true
+			schedule.ClosesInMinutes = &closesIn
+		}
+		wait := (iv.start - current + minutesPerWeek) % minutesPerWeek
+		if wait > 0 && (nextOpen < 0 || wait < nextOpen) {
+			nextOpen = wait
+		}
+	}
+	if !schedule.IsOpenNow && nextOpen > 0 {
+		at := local.Truncate(time.Minute).Add(time.Duration(nextOpen) * time.Minute)
+		schedule.NextOpenAt = &at
+	}
+	return schedule
+}
+
+// periodInterval converts a period to minutes of the week. A close at or
+// before the open is on a later day: the next one when Google repeated the
+// open's day, as some listings do for "18:00–02:00", and otherwise the
+// close's own day in the following week.
+func periodInterval(period maps.OpeningHoursPeriod) (weekInterval, bool) {
+	start, ok := weekMinute(period.Open)
+	if !ok {
+		return weekInterval{}, false
+	}
+	end, ok := weekMinute(period.Close)
+	if !ok {
+		return weekInterval{}, false
+	}
+	if end <= start {
+		if period.Close.Day == period.Open.Day {
+			end += minutesPerDay
+		} else {
+			end += minutesPerWeek
+		}
+	}
+	return weekInterval{start, end}, true
+}
+
+// remaining reports whether the interval covers minute of the week and, if
+// so, how many minutes are left in it. Intervals that started late on
+// Saturday cover the early minutes of the week too.
+func (iv weekInterval) remaining(minute int) (int, bool) {
+	for _, at := range []int{minute, minute + minutesPerWeek} {
+		if at >= iv.start && at < iv.end {
+			return iv.end - at, true
+		}
+	}
+	return 0, false
+}
+
+func weekMinuteAt(local time.Time) int {
+	return int(local.Weekday())*minutesPerDay + local.Hour()*60 + local.Minute()
+}
+
+func weekMinute(t maps.OpeningHoursOpenClose) (int, bool) {
+	if len(t.Time) != 4 {
+		return 0, false
+	}
+	hhmm, err := strconv.Atoi(t.Time)
+	if err != nil || hhmm/100 > 24 || hhmm%100 > 59 {
+		return 0, false
+	}
+	return int(t.Day)*minutesPerDay + hhmm/100*60 + hhmm%100, true
+}
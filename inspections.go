@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	inspectionEnrichLimit = 20
+	inspectionMatchScore  = 0.6
+)
+
+var inspectionCache = newTTLCache(24 * time.Hour)
+
+type Inspection struct {
+	Score  string `json:"score,omitempty"`
+	Grade  string `json:"grade,omitempty"`
+	Date   string `json:"date,omitempty"`
+	Source string `json:"source"`
+}
+
+// inspectionsProvider finds the latest inspection for a result, or nil when
+// no record matches confidently enough.
+type inspectionsProvider interface {
+	Name() string
+	Inspection(ctx context.Context, result maps.PlacesSearchResult) (*Inspection, error)
+}
+
+// socrataInspections queries a city's open-data inspections dataset. Every
+// city names its columns differently, so the column names are configured.
+type socrataInspections struct {
+	Dataset      string
+	AppToken     string
+	NameField    string
+	AddressField string
+	ScoreField   string
+	GradeField   string
+	DateField    string
+}
+
+func configuredInspections() inspectionsProvider {
+	if cfg.InspectionsURL == "" {
+		return nil
+	}
+	return socrataInspections{
+		Dataset:      cfg.InspectionsURL,
+		AppToken:     cfg.InspectionsToken,
+		NameField:    cfg.InspectionsNameField,
+		AddressField: cfg.InspectionsAddressField,
+		ScoreField:   cfg.InspectionsScoreField,
+		GradeField:   cfg.InspectionsGradeField,
+		DateField:    cfg.InspectionsDateField,
+	}
+}
+
+func (s socrataInspections) Name() string {
+	return "socrata"
+}
+
+func (s socrataInspections) Inspection(ctx context.Context, result maps.PlacesSearchResult) (*Inspection, error) {
+	token := longestToken(result.Name)
+	if token == "" {
+		return nil, nil
+	}
+	query := url.Values{
+		"$where": {fmt.Sprintf("upper(%s) like '%%%s%%'", s.NameField, strings.ToUpper(token))},
+		"$order": {s.DateField + " DESC"},
+		"$limit": {"50"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.Dataset+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.AppToken != "" {
+		req.Header.Set("X-App-Token", s.AppToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("socrata: %s", resp.Status)
+	}
+	var records []map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&records)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		name := fmt.Sprint(record[s.NameField])
+		address := fmt.Sprint(record[s.AddressField])
+		if nameSimilarity(name, result.Name) < inspectionMatchScore {
+			continue
+		}
+		if !addressOverlaps(address, result.Vicinity) {
+			continue
+		}
+		return &Inspection{
+			Score:  recordString(record[s.ScoreField]),
+			Grade:  recordString(record[s.GradeField]),
+			Date:   recordString(record[s.DateField]),
+			Source: s.Name(),
+		}, nil
+	}
+	return nil, nil
+}
+
+func recordString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func longestToken(name string) string {
+	longest := ""
+	for _, token := range strings.Fields(strings.ToLower(name)) {
+		token = normalizeName(token)
+		if len(token) > len(longest) {
+			longest = token
+		}
+	}
+	return longest
+}
+
+// nameSimilarity is the Jaccard overlap of the two names' normalized words.
+func nameSimilarity(a, b string) float64 {
+	wordsA := nameWords(a)
+	wordsB := nameWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+func nameWords(name string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		if word = normalizeName(word); word != "" && word != "the" && word != "and" && word != "restaurant" {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// addressOverlaps accepts a record when its street shares any word with the
+// result's vicinity, or when either side has no address to compare.
+func addressOverlaps(recordAddress, vicinity string) bool {
+	record := nameWords(recordAddress)
+	if len(record) == 0 || vicinity == "" {
+		return true
+	}
+	for word := range nameWords(vicinity) {
+		if record[word] {
+			return true
+		}
+	}
+	return false
+}
+
+func enrichInspections(ctx context.Context, biteArray *BiteResponse) {
+	provider := configuredInspections()
+	if provider == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		if i == inspectionEnrichLimit {
+			break
+		}
+		wg.Add(1)
+		go func(result *BiteResult) {
+			defer wg.Done()
+			if cached, ok := inspectionCache.get(result.PlaceID); ok {
+				result.Inspection = cached.(*Inspection)
+				return
+			}
+			inspection, err := provider.Inspection(ctx, result.PlacesSearchResult)
+			if err != nil {
+				errorLogger.Printf("inspections provider %s: %s", provider.Name(), err)
+				return
+			}
+			inspectionCache.set(result.PlaceID, inspection)
+			result.Inspection = inspection
+		}(&biteArray.Results[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+const (
+	tierFree  = "free"
+	tierPaid  = "paid"
+	tierAdmin = "admin"
+)
+
+var defaultRateLimits = map[string]int{
+	tierFree:  60,
+	tierPaid:  600,
+	tierAdmin: 0,
+}
+
+var paidVerbs = map[string]bool{
+	"photo": true,
+}
+
+var adminVerbs = map[string]bool{
+	"createkey":         true,
+	"revokekey":         true,
+	"createdeal":        true,
+	"deletedeal":        true,
+	"createcampaign":    true,
+	"endcampaign":       true,
+	"createlist":        true,
+	"deletelist":        true,
+	"suggestions":       true,
+	"approvesuggestion": true,
+	"rejectsuggestion":  true,
+	"reports":           true,
+	"updatereport":      true,
+}
+
+type clientKey struct {
+	KeyHash   string `dynamodbav:"keyHash" json:"keyId"`
+	ClientID  string `dynamodbav:"clientId" json:"clientId"`
+	Tier      string `dynamodbav:"tier" json:"tier"`
+	RateLimit int    `dynamodbav:"rateLimit" json:"rateLimit"`
+	Revoked   bool   `dynamodbav:"revoked" json:"revoked"`
+	CreatedAt int64  `dynamodbav:"createdAt" json:"createdAt"`
+
+	Tenant *tenantProfile `dynamodbav:"-" json:"-"`
+}
+
+type createdKey struct {
+	APIKey string `json:"apiKey"`
+	clientKey
+}
+
+// allows reports whether the key's tier may call verb. A nil key means key
+// validation is disabled, which leaves everything but the admin verbs open.
+func (k *clientKey) allows(verb string) bool {
+	if k == nil {
+		return !adminVerbs[verb]
+	}
+	if adminVerbs[verb] {
+		return k.Tier == tierAdmin
+	}
+	if paidVerbs[verb] {
+		return k.Tier == tierPaid || k.Tier == tierAdmin
+	}
+	return true
+}
+
+// isAdmin reports whether the key may see internals such as search debug
+// output. Without key validation nobody can.
+func (k *clientKey) isAdmin() bool {
+	return k != nil && k.Tier == tierAdmin
+}
+
+func (k *clientKey) clientID() string {
+	if k == nil {
+		return ""
+	}
+	return k.ClientID
+}
+
+func (k *clientKey) tenant() *tenantProfile {
+	if k == nil {
+		return nil
+	}
+	return k.Tenant
+}
+
+func (k *clientKey) rateLimit() int {
+	if k.RateLimit > 0 {
+		return k.RateLimit
+	}
+	if k.Tenant != nil && k.Tenant.RateLimit > 0 {
+		return k.Tenant.RateLimit
+	}
+	return defaultRateLimits[k.Tier]
+}
+
+func headerValue(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func authorize(ctx context.Context, req events.APIGatewayProxyRequest) (*clientKey, int) {
+	if cfg.APIKeysTable == "" {
+		return nil, http.StatusOK
+	}
+	raw := headerValue(req.Headers, "X-Api-Key")
+	if raw == "" {
+		return nil, http.StatusUnauthorized
+	}
+	key, err := lookupAPIKey(ctx, hashAPIKey(raw))
+	if err != nil {
+		errorLogger.Println(err)
+		return nil, http.StatusInternalServerError
+	}
+	if key == nil || key.Revoked {
+		return nil, http.StatusUnauthorized
+	}
+	key.Tenant, err = loadTenant(ctx, key.ClientID)
+	if err != nil {
+		errorLogger.Println(err)
+		return nil, http.StatusInternalServerError
+	}
+	allowed, err := consumeRateLimit(ctx, key)
+	if err != nil {
+		errorLogger.Println(err)
+		return nil, http.StatusInternalServerError
+	}
+	if !allowed {
+		return nil, http.StatusTooManyRequests
+	}
+	return key, http.StatusOK
+}
+
+func lookupAPIKey(ctx context.Context, keyHash string) (*clientKey, error) {
+	out, err := db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(cfg.APIKeysTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"keyHash": {S: aws.String(keyHash)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Item) == 0 {
+		return nil, nil
+	}
+	var key clientKey
+	err = dynamodbattribute.UnmarshalMap(out.Item, &key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// consumeRateLimit counts the request against a fixed one-minute window
+// shared by every Lambda container, in Redis or DynamoDB per
+// RATE_LIMIT_STORE. The DynamoDB window items expire via the table's
+// expiresAt TTL attribute.
+func consumeRateLimit(ctx context.Context, key *clientKey) (bool, error) {
+	limit := key.rateLimit()
+	if limit <= 0 {
+		return true, nil
+	}
+	window := time.Now().Unix() / 60
+	if cfg.RateLimitStore == storeRedis {
+		return consumeRedisRateLimit(ctx, key.KeyHash, window, limit)
+	}
+	_, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(cfg.APIKeysTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"keyHash": {S: aws.String(fmt.Sprintf("rate#%s#%d", key.KeyHash, window))},
+		},
+		UpdateExpression:    aws.String("ADD requests :one SET expiresAt = :expiresAt"),
+		ConditionExpression: aws.String("attribute_not_exists(requests) OR requests < :limit"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":one":       {N: aws.String("1")},
+			":limit":     {N: aws.String(fmt.Sprint(limit))},
+			":expiresAt": {N: aws.String(fmt.Sprint((window + 2) * 60))},
+		},
+	})
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func handleCreateKey(ctx context.Context, clientID, tier string, rateLimit int) (events.APIGatewayProxyResponse, error) {
+	if clientID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	if tier == "" {
+		tier = tierFree
+	}
+	if _, ok := defaultRateLimits[tier]; !ok {
+		return clientError(http.StatusBadRequest)
+	}
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return serverError(err)
+	}
+	raw := "bite_" + base64.RawURLEncoding.EncodeToString(secret)
+	key := clientKey{
+		KeyHash:   hashAPIKey(raw),
+		ClientID:  clientID,
+		Tier:      tier,
+		RateLimit: rateLimit,
+		CreatedAt: time.Now().Unix(),
+	}
+	item, err := dynamodbattribute.MarshalMap(key)
+	if err != nil {
+		return serverError(err)
+	}
+	_, err = db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(cfg.APIKeysTable),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(keyHash)"),
+	})
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(createdKey{APIKey: raw, clientKey: key})
+}
+
+func handleRevokeKey(ctx context.Context, keyID string) (events.APIGatewayProxyResponse, error) {
+	if keyID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	_, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(cfg.APIKeysTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"keyHash": {S: aws.String(keyID)},
+		},
+		UpdateExpression:    aws.String("SET revoked = :revoked"),
+		ConditionExpression: aws.String("attribute_exists(keyHash)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":revoked": {BOOL: aws.Bool(true)},
+		},
+	})
+	if isConditionalCheckFailed(err) {
+		return clientError(http.StatusNotFound)
+	}
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(map[string]string{"keyId": keyID, "status": "revoked"})
+}
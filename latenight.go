@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	lateNightConcurrency = 5
+	// nightRolloverHour is when "tonight" moves on to the next day: at 00:30
+	// a search for late-night food still means the night that began
+	// yesterday evening.
+	nightRolloverHour = 5
+)
+
+var placeHoursCache = newTTLCache(24 * time.Hour)
+
+// placeHours is what lateNight needs from a place's details: its weekly
+// intervals and the zone they are in.
+type placeHours struct {
+	intervals  []weekInterval
+	alwaysOpen bool
+	loc        *time.Location
+}
+
+var lateNightFields = []maps.PlaceDetailsFieldMask{
+	maps.PlaceDetailsFieldMaskPlaceID,
+	maps.PlaceDetailsFieldMaskGeometry,
+	maps.PlaceDetailsFieldMaskOpeningHours,
+	maps.PlaceDetailsFieldMaskUTCOffset,
+}
+
+func hoursFor(ctx context.Context, placeID string) *placeHours {
+	if cached, ok := placeHoursCache.get(placeID); ok {
+		return cached.(*placeHours)
+	}
+	place, err := respondPlaceDetails(placeID, lateNightFields...)
+	if err != nil {
+		logDetailsError(err)
+		return nil
+	}
+	var hours *placeHours
+	if place.OpeningHours != nil && len(place.OpeningHours.Periods) > 0 {
+		hours = newPlaceHours(place.OpeningHours.Periods, placeTimezone(ctx, place))
+	}
+	placeHoursCache.set(placeID, hours)
+	return hours
+}
+
+func newPlaceHours(periods []maps.OpeningHoursPeriod, loc *time.Location) *placeHours {
+	hours := &placeHours{loc: loc}
+	hours.alwaysOpen = len(periods) == 1 && periods[0].Close.Time == ""
+	for _, period := range periods {
+		if iv, ok := periodInterval(period); ok {
+			hours.intervals = append(hours.intervals, iv)
+		}
+	}
+	sort.Slice(hours.intervals, func(i, j int) bool { return hours.intervals[i].start < hours.intervals[j].start })
+	return hours
+}
+
+// lateNightAt is the moment a place must be open at to count as late night:
+// hour o'clock local time tonight, where hours before noon are after
+// midnight.
+func lateNightAt(now time.Time, hour int) time.Time {
+	night := now
+	if now.Hour() < nightRolloverHour {
+		night = now.AddDate(0, 0, -1)
+	}
+	at := time.Date(night.Year(), night.Month(), night.Day(), hour, 0, 0, 0, now.Location())
+	if hour < 12 {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at
+}
+
+// openLate reports whether a place is open at the late-night hour tonight.
+// Periods closing after midnight are attributed to the day they opened, so a
+// bar open 20:00–02:00 on Friday counts for a 01:00 cutoff on Friday night.
+func (h *placeHours) openLate(now time.Time, hour int) bool {
+	if h.alwaysOpen {
+		return true
+	}
+	minute := weekMinuteAt(lateNightAt(now.In(h.loc), hour))
+	for _, iv := range h.intervals {
+		if _, ok := iv.remaining(minute); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// closesAt is when the interval the place is open in at now ends, in the
+// place's time zone.
+func (h *placeHours) closesAt(now time.Time) (time.Time, bool) {
+	local := now.In(h.loc).Truncate(time.Minute)
+	minute := weekMinuteAt(local)
+	for _, iv := range h.intervals {
+		if remaining, ok := iv.remaining(minute); ok {
+			return local.Add(time.Duration(remaining) * time.Minute), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// filterLateNight keeps results open past cfg.LateNightHour tonight in their
+// own time zone. Places without hours are dropped.
+func filterLateNight(ctx context.Context, biteArray *BiteResponse) {
+	now := time.Now()
+	keep := make([]bool, len(biteArray.Results))
+	sem := make(chan struct{}, lateNightConcurrency)
+	var wg sync.WaitGroup
+	for i, result := range biteArray.Results {
+		wg.Add(1)
+		go func(i int, placeID string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			if hours := hoursFor(ctx, placeID); hours != nil {
+				keep[i] = hours.openLate(now, cfg.LateNightHour)
+			}
+		}(i, result.PlaceID)
+	}
+	wg.Wait()
+	kept := make([]BiteResult, 0, len(biteArray.Results))
+	for i, result := range biteArray.Results {
+		if keep[i] {
+			kept = append(kept, result)
+		}
+	}
+	biteArray.Results = kept
+}
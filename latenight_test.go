@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+func period(openDay time.Weekday, open string, closeDay time.Weekday, close string) maps.OpeningHoursPeriod {
+	return maps.OpeningHoursPeriod{
+		Open:  maps.OpeningHoursOpenClose{Day: openDay, Time: open},
+		Close: maps.OpeningHoursOpenClose{Day: closeDay, Time: close},
+	}
+}
+
+// at is a local time in the week of Sunday 2026-10-11.
+func at(day time.Weekday, hour, minute int) time.Time {
+	return time.Date(2026, time.October, 11+int(day), hour, minute, 0, 0, time.UTC)
+}
+
+func TestPeriodInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		period maps.OpeningHoursPeriod
+		want   weekInterval
+		ok     bool
+	}{
+		{"same day", period(time.Friday, "1100", time.Friday, "2200"), weekInterval{5*minutesPerDay + 660, 5*minutesPerDay + 1320}, true},
+		{"past midnight", period(time.Friday, "2000", time.Saturday, "0200"), weekInterval{5*minutesPerDay + 1200, 6*minutesPerDay + 120}, true},
+		{"close repeats open day", period(time.Friday, "1800", time.Friday, "0200"), weekInterval{5*minutesPerDay + 1080, 6*minutesPerDay + 120}, true},
+		{"saturday into sunday", period(time.Saturday, "2000", time.Sunday, "0200"), weekInterval{6*minutesPerDay + 1200, minutesPerWeek + 120}, true},
+		{"closes at midnight", period(time.Friday, "1800", time.Saturday, "0000"), weekInterval{5*minutesPerDay + 1080, 6 * minutesPerDay}, true},
+		{"no close", maps.OpeningHoursPeriod{Open: maps.OpeningHoursOpenClose{Day: time.Sunday, Time: "0000"}}, weekInterval{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := periodInterval(tt.period)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("periodInterval = %v, %t; want %v, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLateNightAt(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		hour int
+		want time.Time
+	}{
+		{"evening, hour 23", at(time.Friday, 21, 0), 23, at(time.Friday, 23, 0)},
+		{"evening, hour 0", at(time.Friday, 21, 0), 0, at(time.Saturday, 0, 0)},
+		{"23:59, hour 0", at(time.Friday, 23, 59), 0, at(time.Saturday, 0, 0)},
+		{"00:00 is still last night", at(time.Saturday, 0, 0), 23, at(time.Friday, 23, 0)},
+		{"00:30 is still last night", at(time.Saturday, 0, 30), 1, at(time.Saturday, 1, 0)},
+		{"rollover moves to tonight", at(time.Saturday, nightRolloverHour, 0), 23, at(time.Saturday, 23, 0)},
+		{"saturday night into sunday", at(time.Sunday, 0, 30), 1, at(time.Sunday, 1, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lateNightAt(tt.now, tt.hour); !got.Equal(tt.want) {
+				t.Errorf("lateNightAt = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenLate(t *testing.T) {
+	closesAtTwo := []maps.OpeningHoursPeriod{period(time.Friday, "2000", time.Saturday, "0200")}
+	saturdayNight := []maps.OpeningHoursPeriod{period(time.Saturday, "2000", time.Sunday, "0200")}
+	sameDay := []maps.OpeningHoursPeriod{period(time.Friday, "1100", time.Friday, "2200")}
+	repeatedDay := []maps.OpeningHoursPeriod{period(time.Friday, "1800", time.Friday, "0200")}
+	allDay := []maps.OpeningHoursPeriod{{Open: maps.OpeningHoursOpenClose{Day: time.Sunday, Time: "0000"}}}
+
+	tests := []struct {
+		name    string
+		periods []maps.OpeningHoursPeriod
+		now     time.Time
+		hour    int
+		want    bool
+	}{
+		{"23:59 before a 02:00 close", closesAtTwo, at(time.Friday, 23, 59), 1, true},
+		{"00:00 before a 02:00 close", closesAtTwo, at(time.Saturday, 0, 0), 1, true},
+		{"00:30 before a 02:00 close", closesAtTwo, at(time.Saturday, 0, 30), 1, true},
+		{"cutoff at the close", closesAtTwo, at(time.Saturday, 0, 30), 2, false},
+		{"LATE_NIGHT_HOUR 23", closesAtTwo, at(time.Friday, 19, 0), 23, true},
+		{"LATE_NIGHT_HOUR 0", closesAtTwo, at(time.Friday, 19, 0), 0, true},
+		{"LATE_NIGHT_HOUR 0 after midnight", closesAtTwo, at(time.Saturday, 0, 30), 0, true},
+		{"other night", closesAtTwo, at(time.Thursday, 23, 59), 1, false},
+		{"saturday into sunday", saturdayNight, at(time.Saturday, 23, 59), 1, true},
+		{"saturday into sunday after midnight", saturdayNight, at(time.Sunday, 0, 30), 1, true},
+		{"sunday night is not saturday's", saturdayNight, at(time.Sunday, 23, 0), 1, false},
+		{"same-day close, LATE_NIGHT_HOUR 23", sameDay, at(time.Friday, 20, 0), 23, false},
+		{"same-day close, LATE_NIGHT_HOUR 0", sameDay, at(time.Friday, 20, 0), 0, false},
+		{"close repeats the open day", repeatedDay, at(time.Friday, 23, 59), 1, true},
+		{"24h, LATE_NIGHT_HOUR 23", allDay, at(time.Tuesday, 12, 0), 23, true},
+		{"24h, LATE_NIGHT_HOUR 0", allDay, at(time.Tuesday, 0, 30), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours := newPlaceHours(tt.periods, time.UTC)
+			if got := hours.openLate(tt.now, tt.hour); got != tt.want {
+				t.Errorf("openLate = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
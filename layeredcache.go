@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	storeDynamoDB = "dynamodb"
+
+	cacheKeyVersion = "v1"
+	// dynamoCacheMaxBytes keeps values comfortably under DynamoDB's 400KB
+	// item limit; bigger ones are only cached in L1.
+	dynamoCacheMaxBytes = 350 * 1024
+	// cacheHotKeysMax bounds how many keys a cache counts lookups for
+	// between warmups.
+	cacheHotKeysMax = 1000
+)
+
+// cacheKey builds every layered cache key the same way, so the kinds can't
+// collide and a format change can be rolled out by bumping cacheKeyVersion.
+func cacheKey(kind string, parts ...interface{}) string {
+	fields := make([]string, 0, len(parts)+3)
+	fields = append(fields, "bite", cacheKeyVersion, kind)
+	for _, part := range parts {
+		fields = append(fields, fmt.Sprint(part))
+	}
+	return strings.Join(fields, ":")
+}
+
+// cacheBackend is a shared L2 cache that outlives the container. Lease
+// takes a short exclusive claim on key for owner, so that only one container
+// refreshes it; Release gives it back early.
+type cacheBackend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Lease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key, owner string) error
+}
+
+var cacheL2 struct {
+	once    sync.Once
+	backend cacheBackend
+}
+
+func configuredCacheL2() cacheBackend {
+	cacheL2.once.Do(func() {
+		switch cfg.CacheL2 {
+		case storeDynamoDB:
+			cacheL2.backend = dynamoCache{table: cfg.CacheTable}
+		case storeRedis:
+			cacheL2.backend = redisCache{client: redisClient()}
+		}
+	})
+	return cacheL2.backend
+}
+
+// layeredCache is a per-container LRU in front of the configured L2. Values
+// are stored as JSON so both layers hold the same thing. Lookups are counted
+// per key so warmup can reload the hottest ones.
+type layeredCache struct {
+	name string
+	ttl  time.Duration
+	l1   *ttlCache
+
+	mu      sync.Mutex
+	lookups map[string]int
+}
+
+func newLayeredCache(name string, ttl time.Duration) *layeredCache {
+	return &layeredCache{name: name, ttl: ttl, l1: newTTLCache(ttl)}
+}
+
+// get decodes the cached value for key into out, reporting whether there
+// was one.
+func (c *layeredCache) get(ctx context.Context, key string, out interface{}) bool {
+	c.countLookup(key)
+	var data []byte
+	cached, ok := c.l1.get(key)
+	if ok {
+		data = cached.([]byte)
+	}
+	layer := "L1"
+	if !ok {
+		if l2 := configuredCacheL2(); l2 != nil {
+			var err error
+			data, ok, err = l2.Get(ctx, key)
+			if err != nil {
+				errorLogger.Println(err)
+			}
+			if ok {
+				layer = "L2"
+				c.l1.set(key, data)
+			}
+		}
+	}
+	if !ok || json.Unmarshal(data, out) != nil {
+		emitCacheMetric(c.name, "Miss")
+		return false
+	}
+	emitCacheMetric(c.name, layer+"Hit")
+	return true
+}
+
+func (c *layeredCache) set(ctx context.Context, key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		errorLogger.Println(err)
+		return
+	}
+	c.l1.set(key, data)
+	if l2 := configuredCacheL2(); l2 != nil {
+		err = l2.Set(ctx, key, data, c.ttl)
+		if err != nil {
+			errorLogger.Println(err)
+		}
+	}
+}
+
+func (c *layeredCache) countLookup(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lookups == nil {
+		c.lookups = map[string]int{}
+	}
+	if _, ok := c.lookups[key]; ok || len(c.lookups) < cacheHotKeysMax {
+		c.lookups[key]++
+	}
+}
+
+// reloadHottest copies the n most looked-up keys since the last reload from
+// L2 into L1, so the container serves what other containers have refreshed
+// since. Keys L2 no longer has are dropped from L1. It returns how many keys
+// were reloaded.
+func (c *layeredCache) reloadHottest(ctx context.Context, n int) int {
+	l2 := configuredCacheL2()
+	if l2 == nil {
+		return 0
+	}
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.lookups))
+	for key := range c.lookups {
+		keys = append(keys, key)
+	}
+	counts := c.lookups
+	c.lookups = nil
+	c.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool { return counts[keys[i]] > counts[keys[j]] })
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	reloaded := 0
+	for _, key := range keys {
+		data, ok, err := l2.Get(ctx, key)
+		if err != nil {
+			errorLogger.Println(err)
+			continue
+		}
+		if ok {
+			c.l1.set(key, data)
+		} else {
+			c.l1.delete(key)
+		}
+		reloaded++
+	}
+	return reloaded
+}
+
+func emitCacheMetric(cache, outcome string) {
+	emitDimensionedMetric("Cache"+outcome, "Count", 1, map[string]string{"Cache": cache})
+}
+
+// dynamoCache is the DynamoDB L2. The table is keyed by cacheKey and should
+// have TTL enabled on expiresAt; reads check it too, since DynamoDB deletes
+// expired items lazily.
+type dynamoCache struct {
+	table string
+}
+
+func (d dynamoCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	out, err := db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"cacheKey": {S: aws.String(key)},
+		},
+	})
+	if err != nil || len(out.Item) == 0 {
+		return nil, false, err
+	}
+	var expiresAt int64
+	if n := out.Item["expiresAt"]; n != nil && n.N != nil {
+		fmt.Sscan(*n.N, &expiresAt)
+	}
+	value := out.Item["value"]
+	if value == nil || time.Now().Unix() >= expiresAt {
+		return nil, false, nil
+	}
+	return value.B, true, nil
+}
+
+func (d dynamoCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if len(value) > dynamoCacheMaxBytes {
+		return nil
+	}
+	_, err := db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"cacheKey":  {S: aws.String(key)},
+			"value":     {B: value},
+			"expiresAt": {N: aws.String(fmt.Sprint(time.Now().Add(ttl).Unix()))},
+		},
+	})
+	return err
+}
+
+// Lease writes a lease item unless an unexpired one exists.
+func (d dynamoCache) Lease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	_, err := db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"cacheKey":  {S: aws.String(key)},
+			"owner":     {S: aws.String(owner)},
+			"expiresAt": {N: aws.String(fmt.Sprint(now.Add(ttl).Unix()))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(cacheKey) OR expiresAt <= :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(fmt.Sprint(now.Unix()))},
+		},
+	})
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Release deletes the lease only if owner still holds it.
+func (d dynamoCache) Release(ctx context.Context, key, owner string) error {
+	_, err := db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"cacheKey": {S: aws.String(key)},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String("owner"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(owner)},
+		},
+	})
+	if isConditionalCheckFailed(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"googlemaps.github.io/maps"
+)
+
+const (
+	listsCacheKey       = "lists"
+	maxNearbyLists      = 10
+	defaultListsRadius  = 5000
+	listHydrateParallel = 5
+)
+
+var listsCache = newTTLCache(5 * time.Minute)
+
+type CuratedList struct {
+	ListID       string          `dynamodbav:"listId" json:"listId"`
+	Title        string          `dynamodbav:"title" json:"title"`
+	Description  string          `dynamodbav:"description" json:"description,omitempty"`
+	Lat          float64         `dynamodbav:"lat" json:"lat"`
+	Long         float64         `dynamodbav:"long" json:"long"`
+	RadiusMeters uint            `dynamodbav:"radiusMeters" json:"radiusMeters"`
+	PlaceIDs     []string        `dynamodbav:"placeIds" json:"placeIds"`
+	CreatedAt    int64           `dynamodbav:"createdAt" json:"createdAt"`
+	Places       []*PlaceSummary `dynamodbav:"-" json:"places,omitempty"`
+}
+
+func handleCreateList(ctx context.Context, title, description string, lat, long float64, radius uint, placeIDs []string) (events.APIGatewayProxyResponse, error) {
+	if cfg.ListsTable == "" || title == "" || len(placeIDs) == 0 {
+		return clientError(http.StatusBadRequest)
+	}
+	if radius == 0 {
+		radius = defaultListsRadius
+	}
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		return serverError(err)
+	}
+	list := CuratedList{
+		ListID:       hex.EncodeToString(id),
+		Title:        sanitizeText(title),
+		Description:  sanitizeText(description),
+		Lat:          lat,
+		Long:         long,
+		RadiusMeters: radius,
+		PlaceIDs:     placeIDs,
+		CreatedAt:    time.Now().Unix(),
+	}
+	item, err := dynamodbattribute.MarshalMap(list)
+	if err != nil {
+		return serverError(err)
+	}
+	_, err = db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(cfg.ListsTable),
+		Item:      item,
+	})
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(list)
+}
+
+func handleDeleteList(ctx context.Context, listID string) (events.APIGatewayProxyResponse, error) {
+	if cfg.ListsTable == "" || listID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	_, err := db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(cfg.ListsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"listId": {S: aws.String(listID)},
+		},
+		ConditionExpression: aws.String("attribute_exists(listId)"),
+	})
+	if isConditionalCheckFailed(err) {
+		return clientError(http.StatusNotFound)
+	}
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(map[string]string{"listId": listID, "status": "deleted"})
+}
+
+// handleNearbyLists returns the lists whose area overlaps the user's search
+// circle, closest first, with their entries hydrated into place summaries.
+func handleNearbyLists(ctx context.Context, lat, long float64, radius uint) (events.APIGatewayProxyResponse, error) {
+	if cfg.ListsTable == "" {
+		return clientError(http.StatusNotFound)
+	}
+	lists, err := allLists(ctx)
+	if err != nil {
+		return serverError(err)
+	}
+	location := maps.LatLng{Lat: lat, Lng: long}
+	distances := map[string]float64{}
+	nearby := []CuratedList{}
+	for _, list := range lists {
+		d := distanceMeters(location, maps.LatLng{Lat: list.Lat, Lng: list.Long})
+		if d <= float64(list.RadiusMeters+radius) {
+			distances[list.ListID] = d
+			nearby = append(nearby, list)
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		return distances[nearby[i].ListID] < distances[nearby[j].ListID]
+	})
+	if len(nearby) > maxNearbyLists {
+		nearby = nearby[:maxNearbyLists]
+	}
+	for i := range nearby {
+		nearby[i].Places = hydratePlaces(nearby[i].PlaceIDs)
+		refreshListPlaceIDs(ctx, &nearby[i])
+	}
+	return jsonSuccess(map[string]interface{}{"lists": nearby})
+}
+
+func allLists(ctx context.Context) ([]CuratedList, error) {
+	if cached, ok := listsCache.get(listsCacheKey); ok {
+		return cached.([]CuratedList), nil
+	}
+	var lists []CuratedList
+	input := &dynamodb.ScanInput{TableName: aws.String(cfg.ListsTable)}
+	for {
+		out, err := db.ScanWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		var page []CuratedList
+		err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &page)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, page...)
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+	listsCache.set(listsCacheKey, lists)
+	return lists, nil
+}
+
+// hydratePlaces looks up summaries for placeIDs in order, leaving out any
+// that fail to load.
+func hydratePlaces(placeIDs []string) []*PlaceSummary {
+	summaries := lookupSummaries(placeIDs)
+	hydrated := summaries[:0]
+	for _, summary := range summaries {
+		if summary != nil {
+			hydrated = append(hydrated, summary)
+		}
+	}
+	return hydrated
+}
+
+// lookupSummaries loads summaries for placeIDs concurrently, in order, with
+// nil for any that fail.
+func lookupSummaries(placeIDs []string) []*PlaceSummary {
+	summaries := make([]*PlaceSummary, len(placeIDs))
+	sem := make(chan struct{}, listHydrateParallel)
+	var wg sync.WaitGroup
+	for i, placeID := range placeIDs {
+		wg.Add(1)
+		go func(i int, placeID string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			summary, err := placeSummary(placeID)
+			if err != nil {
+				logDetailsError(err)
+				return
+			}
+			summaries[i] = summary
+		}(i, placeID)
+	}
+	wg.Wait()
+	return summaries
+}
+
+// refreshListPlaceIDs rewrites a list's stored place IDs when hydration found
+// that Google replaced any of them, or no longer knows them, so the refresh
+// is only paid for once. Places that are gone are dropped from the list.
+func refreshListPlaceIDs(ctx context.Context, list *CuratedList) {
+	refreshed := map[string]string{}
+	removed := map[string]bool{}
+	places := list.Places[:0]
+	for _, place := range list.Places {
+		if place.NotFound {
+			removed[place.PlaceID] = true
+			continue
+		}
+		if place.RefreshedFrom != "" {
+			refreshed[place.RefreshedFrom] = place.PlaceID
+		}
+		places = append(places, place)
+	}
+	list.Places = places
+	if len(refreshed) == 0 && len(removed) == 0 {
+		return
+	}
+	placeIDs := make([]string, 0, len(list.PlaceIDs))
+	for _, placeID := range list.PlaceIDs {
+		if removed[placeID] {
+			continue
+		}
+		if current, ok := refreshed[placeID]; ok {
+			placeID = current
+		}
+		placeIDs = append(placeIDs, placeID)
+	}
+	ids, err := dynamodbattribute.Marshal(placeIDs)
+	if err != nil {
+		errorLogger.Println(err)
+		return
+	}
+	_, err = db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(cfg.ListsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"listId": {S: aws.String(list.ListID)},
+		},
+		UpdateExpression:    aws.String("SET placeIds = :ids"),
+		ConditionExpression: aws.String("attribute_exists(listId)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":ids": ids,
+		},
+	})
+	if err != nil && !isConditionalCheckFailed(err) {
+		errorLogger.Println(err)
+		return
+	}
+	emitMetric("PlaceIDRefreshed", "Count", float64(len(refreshed)))
+	emitMetric("PlaceIDRemoved", "Count", float64(len(removed)))
+	list.PlaceIDs = placeIDs
+	listsCache.delete(listsCacheKey)
+}
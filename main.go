@@ -9,6 +9,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -16,18 +18,122 @@ import (
 )
 
 type BiteBody struct {
-	Verb      string  `json:"verb"`
-	Long      float64 `json:"long"`
-	Lat       float64 `json:"lat"`
-	Radius    uint    `json:"radius"`
-	MinPrice  int     `json:"minPrice"`
-	MaxPrice  int     `json:"maxPrice"`
-	PageToken string  `json:"pageToken"`
-	PhotoRef  string  `json:"photoRef"`
+	Verb                     string   `json:"verb"`
+	Long                     float64  `json:"long"`
+	Lat                      float64  `json:"lat"`
+	Radius                   uint     `json:"radius"`
+	MinPrice                 int      `json:"minPrice"`
+	MaxPrice                 int      `json:"maxPrice"`
+	MaxPerPerson             float64  `json:"maxPerPerson"`
+	PageToken                string   `json:"pageToken"`
+	PhotoRef                 string   `json:"photoRef"`
+	ClientID                 string   `json:"clientId"`
+	Tier                     string   `json:"tier"`
+	RateLimit                int      `json:"rateLimit"`
+	KeyID                    string   `json:"keyId"`
+	Privacy                  bool     `json:"privacy"`
+	Count                    int      `json:"count"`
+	GroupSize                int      `json:"groupSize"`
+	ExportFormat             string   `json:"exportFormat"`
+	PlaceID                  string   `json:"placeId"`
+	StartTime                string   `json:"startTime"`
+	Duration                 int      `json:"durationMinutes"`
+	IncludeMealPrice         bool     `json:"includeMealPrice"`
+	IncludeInspections       bool     `json:"includeInspections"`
+	Accessible               bool     `json:"accessible"`
+	GoodForKids              bool     `json:"goodForKids"`
+	AllowsDogs               bool     `json:"allowsDogs"`
+	ServesBeer               bool     `json:"servesBeer"`
+	ServesWine               bool     `json:"servesWine"`
+	ServesCocktails          bool     `json:"servesCocktails"`
+	ExcludeBarsOnly          bool     `json:"excludeBarsOnly"`
+	LateNight                bool     `json:"lateNight"`
+	MealType                 string   `json:"mealType"`
+	DriveThrough             bool     `json:"driveThrough"`
+	MinResults               int      `json:"minResults"`
+	IncludeAttributes        bool     `json:"includeAttributes"`
+	IncludeParking           bool     `json:"includeParking"`
+	IncludeTransit           bool     `json:"includeTransit"`
+	IncludeAmbiance          bool     `json:"includeAmbiance"`
+	Ambiance                 []string `json:"ambiance"`
+	Kind                     string   `json:"kind"`
+	Value                    string   `json:"value"`
+	Status                   string   `json:"status"`
+	SuggestionID             string   `json:"suggestionId"`
+	Category                 string   `json:"category"`
+	Details                  string   `json:"details"`
+	ReportID                 string   `json:"reportId"`
+	Title                    string   `json:"title"`
+	Description              string   `json:"description"`
+	EndTime                  string   `json:"endTime"`
+	DealID                   string   `json:"dealId"`
+	Budget                   int      `json:"budget"`
+	CampaignID               string   `json:"campaignId"`
+	ListID                   string   `json:"listId"`
+	PlaceIDs                 []string `json:"placeIds"`
+	PageSize                 int      `json:"pageSize"`
+	SortBy                   string   `json:"sortBy"`
+	IncludeClosed            bool     `json:"includeClosed"`
+	ExcludeTemporarilyClosed bool     `json:"excludeTemporarilyClosed"`
+	Debug                    bool     `json:"debug"`
+	IncludeSummary           bool     `json:"includeSummary"`
+	Query                    string   `json:"query"`
+	Keyword                  string   `json:"keyword"`
+	Cuisine                  string   `json:"cuisine"`
+	AcceptLanguage           string   `json:"-"`
+}
+
+type BiteResponse struct {
+	Results          []BiteResult
+	HTMLAttributions []string
+	NextPageToken    string
+	Meta             *ResponseMeta `json:"meta,omitempty"`
+}
+
+type BiteResult struct {
+	maps.PlacesSearchResult
+	Sources            []string                `json:"sources,omitempty"`
+	SourceRatings      map[string]SourceRating `json:"sourceRatings,omitempty"`
+	Score              float64                 `json:"score"`
+	EstimatedMealPrice *MealPrice              `json:"estimatedMealPrice,omitempty"`
+	PerPerson          *PerPersonEstimate      `json:"perPerson,omitempty"`
+	Ambiance           []string                `json:"ambiance,omitempty"`
+	CommunityTags      []string                `json:"communityTags,omitempty"`
+	Attributes         *PlaceAttributes        `json:"attributes,omitempty"`
+	DriveThrough       bool                    `json:"driveThrough,omitempty"`
+	Inspection         *Inspection             `json:"inspection,omitempty"`
+	Parking            []ParkingOption         `json:"parking,omitempty"`
+	Transit            *TransitAccess          `json:"transit,omitempty"`
+	Deals              []Deal                  `json:"deals,omitempty"`
+	Sponsored          *SponsoredLabel         `json:"sponsored,omitempty"`
+	Display            *DisplayStrings         `json:"display,omitempty"`
+	TemporarilyClosed  bool                    `json:"temporarilyClosed,omitempty"`
+	Similarity         float64                 `json:"similarity,omitempty"`
+	Cuisines           []string                `json:"cuisines,omitempty"`
+}
+
+type ResponseMeta struct {
+	Privacy  *PrivacyMeta                 `json:"privacy,omitempty"`
+	Branding *TenantBranding              `json:"branding,omitempty"`
+	Filters  map[string]map[string]string `json:"filters,omitempty"`
+	Locale   string                       `json:"locale,omitempty"`
+	Query    *QueryInterpretation         `json:"query,omitempty"`
+	// DidYouMean is only set when a keyword search came back empty.
+	DidYouMean []string     `json:"didYouMean,omitempty"`
+	Debug      *SearchDebug `json:"debug,omitempty"`
+	Relaxed    []Relaxation `json:"relaxed,omitempty"`
+	// Stale is set when a provider was down and its last good results for
+	// the area, fetched at DataAsOf, were served instead.
+	Stale    bool       `json:"stale,omitempty"`
+	DataAsOf *time.Time `json:"dataAsOf,omitempty"`
+	// BudgetConfidence is low when maxPerPerson couldn't be applied because
+	// prices in the search area aren't known.
+	BudgetConfidence string `json:"budgetConfidence,omitempty"`
+
+	timings []ProviderTiming
 }
 
 var errorLogger = log.New(os.Stderr, "ERROR ", log.Llongfile)
-var apiKey = os.Getenv("API_KEY")
 
 func check(err error) {
 	if err != nil {
@@ -36,60 +142,406 @@ func check(err error) {
 }
 
 func main() {
-	lambda.Start(router)
+	if cfgErr != nil {
+		errorLogger.Fatal(cfgErr)
+	}
+	if err := cfg.validate(); err != nil {
+		errorLogger.Fatal(err)
+	}
+	lambda.Start(handleEvent)
 }
 
 func router(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	switch req.HTTPMethod {
 	case "POST":
-		return handleRequest(req)
+		if status := verifySignature(req); status != http.StatusOK {
+			return clientError(status)
+		}
+		key, status := authorize(ctx, req)
+		if status != http.StatusOK {
+			return clientError(status)
+		}
+		return handleRequest(ctx, req, key)
+	case "GET":
+		if strings.HasPrefix(req.Path, "/share/") {
+			return routeShare(req)
+		}
+		if req.Path == "/about" {
+			return handleVersion()
+		}
+		return clientError(http.StatusNotFound)
 	default:
 		log.Printf("%s", req.HTTPMethod)
 		return clientError(http.StatusMethodNotAllowed)
 	}
 }
 
-func handleRequest(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var parameters BiteBody
-	body := req.Body
-	json.Unmarshal([]byte(body), &parameters)
+func handleRequest(ctx context.Context, req events.APIGatewayProxyRequest, key *clientKey) (events.APIGatewayProxyResponse, error) {
+	parameters, bodyErr := decodeBody(req.Body)
+	if bodyErr != nil {
+		return badRequest(bodyErr)
+	}
+	parameters.AcceptLanguage = headerValue(req.Headers, "Accept-Language")
+	// With privacy set, no verb sees the caller's exact location, so it can't
+	// reach a provider, a log line or an image URL.
+	if parameters.Privacy && (parameters.Lat != 0 || parameters.Long != 0) {
+		parameters.Lat, parameters.Long = snapToGrid(parameters.Lat, parameters.Long)
+	}
+	verb := parameters.Verb
+	if !key.allows(verb) || parameters.Debug && !key.isAdmin() {
+		return clientError(http.StatusForbidden)
+	}
+	return dispatchVerb(withVerb(ctx, verb), req, key, parameters)
+}
+
+func dispatchVerb(ctx context.Context, req events.APIGatewayProxyRequest, key *clientKey, parameters BiteBody) (events.APIGatewayProxyResponse, error) {
 	verb := parameters.Verb
 	if verb == "create" {
-		return handleCreate(parameters.Lat, parameters.Long, parameters.Radius, parameters.MinPrice, parameters.MaxPrice)
+		return handleCreate(ctx, parameters, key.tenant())
 	} else if verb == "nextpage" {
-		return handleNext(parameters.PageToken)
+		return handleNext(ctx, parameters, key.tenant())
 	} else if verb == "photo" {
 		return handlePhoto(parameters.PhotoRef)
+	} else if verb == "mapimage" {
+		return handleMapImage(ctx, key.tenant(), parameters.Lat, parameters.Long, parameters.Radius, parameters.MinPrice, parameters.MaxPrice, parameters.Count, parameters.Privacy)
+	} else if verb == "export" {
+		return handleExport(ctx, parameters, key.tenant())
+	} else if verb == "calendar" {
+		return handleCalendar(parameters.PlaceID, parameters.StartTime, parameters.Duration)
+	} else if verb == "share" {
+		return handleShare(parameters.PlaceID)
+	} else if verb == "menu" {
+		return handleMenu(ctx, parameters.PlaceID)
+	} else if verb == "details" {
+		return handleDetails(parameters)
+	} else if verb == "createdeal" {
+		return handleCreateDeal(ctx, parameters.PlaceID, parameters.Title, parameters.Description, parameters.StartTime, parameters.EndTime)
+	} else if verb == "deletedeal" {
+		return handleDeleteDeal(ctx, parameters.PlaceID, parameters.DealID)
+	} else if verb == "sponsoredclick" {
+		return handleSponsoredClick(ctx, parameters.CampaignID)
+	} else if verb == "createcampaign" {
+		return handleCreateCampaign(ctx, parameters.PlaceID, parameters.Lat, parameters.Long, parameters.Radius, parameters.StartTime, parameters.EndTime, parameters.Budget)
+	} else if verb == "endcampaign" {
+		return handleEndCampaign(ctx, parameters.CampaignID)
+	} else if verb == "lists.nearby" {
+		return handleNearbyLists(ctx, parameters.Lat, parameters.Long, parameters.Radius)
+	} else if verb == "createlist" {
+		return handleCreateList(ctx, parameters.Title, parameters.Description, parameters.Lat, parameters.Long, parameters.Radius, parameters.PlaceIDs)
+	} else if verb == "deletelist" {
+		return handleDeleteList(ctx, parameters.ListID)
+	} else if verb == "createkey" {
+		return handleCreateKey(ctx, parameters.ClientID, parameters.Tier, parameters.RateLimit)
+	} else if verb == "revokekey" {
+		return handleRevokeKey(ctx, parameters.KeyID)
+	} else if verb == "taxonomy" {
+		return handleTaxonomy(ctx, req, parameters.AcceptLanguage, parameters.Lat, parameters.Long)
+	} else if verb == "similar" {
+		return handleSimilar(ctx, parameters, key.tenant())
+	} else if verb == "gallery" {
+		return handleGallery(parameters.PlaceID, parameters.Count)
+	} else if verb == "places.batch" {
+		return handlePlacesBatch(parameters.PlaceIDs)
+	} else if verb == "suggest" {
+		return handleSuggest(ctx, key.clientID(), parameters.PlaceID, parameters.Kind, parameters.Value, parameters.Description)
+	} else if verb == "suggestions" {
+		return handleListSuggestions(ctx, parameters.Status)
+	} else if verb == "approvesuggestion" {
+		return handleReviewSuggestion(ctx, parameters.SuggestionID, suggestionApproved)
+	} else if verb == "rejectsuggestion" {
+		return handleReviewSuggestion(ctx, parameters.SuggestionID, suggestionRejected)
+	} else if verb == "report" {
+		return handleReport(ctx, key.clientID(), parameters.PlaceID, parameters.Category, parameters.Details)
+	} else if verb == "reports" {
+		return handleListReports(ctx, parameters.Status)
+	} else if verb == "updatereport" {
+		return handleUpdateReport(ctx, parameters.ReportID, parameters.Status, parameters.Details)
+	} else if verb == "splitbill" {
+		return handleSplitBill(ctx, parameters.PlaceID, parameters.GroupSize)
+	} else if verb == "version" || verb == "about" {
+		return handleVersion()
 	} else {
 		return clientError(http.StatusBadRequest)
 	}
 }
 
-func handleCreate(lat, long float64, radius uint, minPrice, maxPrice int) (events.APIGatewayProxyResponse, error) {
-	biteArray := respondBiteArray(lat, long, radius, minPrice, maxPrice)
-	return clientSuccess(biteArray), nil
+func handleCreate(ctx context.Context, parameters BiteBody, tenant *tenantProfile) (events.APIGatewayProxyResponse, error) {
+	if bodyErr := validateCreate(parameters); bodyErr != nil {
+		return badRequest(bodyErr)
+	}
+	biteArray, err := createPage(ctx, parameters, tenant)
+	if err != nil {
+		return serverError(err)
+	}
+	localizeResults(ctx, &biteArray, parameters.AcceptLanguage, &maps.LatLng{Lat: parameters.Lat, Lng: parameters.Long})
+	return respondBites(biteArray, parameters.ExportFormat)
 }
 
-func handleNext(pagetoken string) (events.APIGatewayProxyResponse, error) {
-	biteArray := respondNextPage(pagetoken)
-	jsonBiteArray, err := json.Marshal(biteArray)
-	check(err)
-	return events.APIGatewayProxyResponse{
-		StatusCode:      http.StatusOK,
-		Headers:         map[string]string{"Content-Type": "application/json", "Access-Control-Allow-Origin": "*"},
-		IsBase64Encoded: false,
-		Body:            string(jsonBiteArray),
-	}, nil
+// createPage runs a validated create request and returns its first page,
+// caching the whole result set for the page cursors when it is paginated.
+func createPage(ctx context.Context, parameters BiteBody, tenant *tenantProfile) (BiteResponse, error) {
+	if parameters.SortBy != "" && parameters.PageSize == 0 {
+		parameters.PageSize = maxPageSize
+	}
+	biteArray, err := searchCreate(ctx, parameters, tenant)
+	if err != nil {
+		return BiteResponse{}, err
+	}
+	if parameters.PageSize > 0 {
+		query := parameters
+		query.Debug = false
+		cursor := pageCursor{Query: &query, Size: parameters.PageSize}
+		pageCache.set(ctx, cursor.key(), biteArray)
+		biteArray.Meta.Debug.cached("page", cacheStore)
+		biteArray = paginate(biteArray, cursor)
+	}
+	return biteArray, nil
+}
+
+// validateCreate checks a create request's parameters. Cursors carrying a
+// create request are checked again when decoded.
+func validateCreate(parameters BiteBody) *BodyError {
+	if !validPageSize(parameters.PageSize) {
+		return pageSizeError
+	}
+	if !validSortBy(parameters.SortBy) {
+		return sortByError
+	}
+	if !validAmbiance(parameters.Ambiance) {
+		return ambianceError
+	}
+	if _, ok := mealTypeFor(parameters.MealType); !ok {
+		return mealTypeError
+	}
+	if parameters.MinResults < 0 || parameters.MinResults > maxPageSize {
+		return minResultsError
+	}
+	return nil
+}
+
+// searchCreate runs a create search with every requested filter and
+// enrichment applied.
+func searchCreate(ctx context.Context, parameters BiteBody, tenant *tenantProfile) (BiteResponse, error) {
+	var interpretation *QueryInterpretation
+	if parameters.Query != "" {
+		parsed := parseQuery(ctx, parameters.Query)
+		parsed.apply(&parameters)
+		interpretation = &parsed
+	}
+	params := searchParams{
+		Lat:      parameters.Lat,
+		Long:     parameters.Long,
+		Radius:   parameters.Radius,
+		MinPrice: parameters.MinPrice,
+		MaxPrice: parameters.MaxPrice,
+		Keyword:  parameters.Keyword,
+		MealType: parameters.MealType,
+	}
+	if tenant != nil && params.Radius == 0 {
+		params.Radius = tenant.DefaultRadius
+	}
+	var debug *SearchDebug
+	if parameters.Debug {
+		debug = &SearchDebug{}
+	}
+	biteArray, err := filteredSearch(ctx, parameters, params, tenant, debug)
+	if err != nil {
+		return BiteResponse{}, err
+	}
+	relaxed, err := relaxSearch(ctx, &biteArray, parameters, &params, tenant, debug)
+	if err != nil {
+		return BiteResponse{}, err
+	}
+	stage := func(filter string, apply func()) {
+		before := len(biteArray.Results)
+		apply()
+		debug.filtered(filter, before, len(biteArray.Results))
+	}
+	if parameters.IncludeMealPrice {
+		enrichMealPrices(ctx, &biteArray)
+	}
+	if parameters.IncludeInspections {
+		enrichInspections(ctx, &biteArray)
+	}
+	if parameters.IncludeParking {
+		enrichParking(ctx, &biteArray)
+	}
+	if parameters.IncludeTransit {
+		enrichTransit(ctx, &biteArray)
+	}
+	enrichDeals(ctx, &biteArray)
+	if parameters.SortBy != "" {
+		sortResults(&biteArray, parameters.SortBy, params)
+	}
+	stage("sponsored", func() { injectSponsored(ctx, parameters, params, &biteArray) })
+	debug.explainScores(biteArray)
+	biteArray.Meta.Debug = debug
+	if parameters.Privacy {
+		biteArray.Meta.Privacy = privacyMeta()
+	}
+	if tenant != nil {
+		biteArray.Meta.Branding = tenant.Branding
+	}
+	biteArray.Meta.Query = interpretation
+	biteArray.Meta.Relaxed = relaxed
+	if params.Keyword != "" && len(biteArray.Results) == 0 {
+		biteArray.Meta.DidYouMean = suggestCorrections(params.Keyword)
+	}
+	return biteArray, nil
+}
+
+// filteredSearch searches the providers and applies every filter in
+// parameters. Enrichment that doesn't filter is left to searchCreate, so
+// relaxSearch can repeat this cheaply.
+func filteredSearch(ctx context.Context, parameters BiteBody, params searchParams, tenant *tenantProfile, debug *SearchDebug) (BiteResponse, error) {
+	biteArray, err := searchPlaces(ctx, params, providersFor(tenant))
+	if err != nil {
+		return BiteResponse{}, err
+	}
+	if debug != nil {
+		debug.Providers = append(debug.Providers, biteArray.Meta.timings...)
+	}
+	applyFilters(ctx, &biteArray, parameters, params, debug)
+	return biteArray, nil
+}
+
+// applyFilters runs every filter in parameters over results that already
+// match the provider-side filters in params.
+func applyFilters(ctx context.Context, biteArray *BiteResponse, parameters BiteBody, params searchParams, debug *SearchDebug) {
+	stage := func(filter string, apply func()) {
+		before := len(biteArray.Results)
+		apply()
+		debug.filtered(filter, before, len(biteArray.Results))
+	}
+	if parameters.SortBy != "" {
+		stage("aggregatePages", func() { aggregatePages(ctx, biteArray) })
+	}
+	enrichCommunity(ctx, biteArray)
+	stage("closures", func() {
+		filterClosures(biteArray, parameters.IncludeClosed, parameters.ExcludeTemporarilyClosed)
+	})
+	stage("cuisine", func() { classifyResults(biteArray, parameters.Cuisine) })
+	if parameters.Accessible {
+		stage("accessible", func() { filterAccessible(ctx, biteArray) })
+	}
+	if parameters.IncludeAttributes || parameters.GoodForKids || parameters.AllowsDogs || parameters.ServesBeer || parameters.ServesWine || parameters.ServesCocktails {
+		enrichAttributes(ctx, biteArray)
+	}
+	if parameters.GoodForKids {
+		stage("goodForKids", func() {
+			filterAttribute(biteArray, func(a *PlaceAttributes) *bool { return a.GoodForChildren })
+		})
+	}
+	if parameters.AllowsDogs {
+		stage("allowsDogs", func() {
+			filterAttribute(biteArray, func(a *PlaceAttributes) *bool { return a.AllowsDogs })
+		})
+	}
+	if parameters.ServesBeer {
+		stage("servesBeer", func() {
+			filterAttribute(biteArray, func(a *PlaceAttributes) *bool { return a.ServesBeer })
+		})
+	}
+	if parameters.ServesWine {
+		stage("servesWine", func() {
+			filterAttribute(biteArray, func(a *PlaceAttributes) *bool { return a.ServesWine })
+		})
+	}
+	if parameters.ServesCocktails {
+		stage("servesCocktails", func() {
+			filterAttribute(biteArray, func(a *PlaceAttributes) *bool { return a.ServesCocktails })
+		})
+	}
+	if parameters.ExcludeBarsOnly {
+		stage("excludeBarsOnly", func() { excludeBars(biteArray) })
+	}
+	if parameters.DriveThrough {
+		stage("driveThrough", func() { filterDriveThrough(ctx, biteArray) })
+	}
+	if parameters.LateNight {
+		stage("lateNight", func() { filterLateNight(ctx, biteArray) })
+	}
+	if parameters.MaxPerPerson > 0 {
+		stage("maxPerPerson", func() {
+			filterBudget(ctx, biteArray, parameters.MaxPerPerson, maps.LatLng{Lat: params.Lat, Lng: params.Long})
+		})
+	}
+	if parameters.IncludeAmbiance || len(parameters.Ambiance) > 0 {
+		enrichAmbiance(ctx, biteArray)
+	}
+	if len(parameters.Ambiance) > 0 {
+		stage("ambiance", func() { filterAmbiance(biteArray, parameters.Ambiance) })
+	}
+}
+
+func handleNext(ctx context.Context, parameters BiteBody, tenant *tenantProfile) (events.APIGatewayProxyResponse, error) {
+	if !validPageSize(parameters.PageSize) {
+		return badRequest(pageSizeError)
+	}
+	biteArray, origin, err := nextPage(ctx, parameters, tenant)
+	if err != nil {
+		return serverError(err)
+	}
+	localizeResults(ctx, &biteArray, parameters.AcceptLanguage, origin)
+	return respondBites(biteArray, parameters.ExportFormat)
+}
+
+// nextPage returns the page a nextpage request's token names, whether it is
+// one of our cursors or a bare Google token, along with the search's origin
+// when the cursor carries it.
+func nextPage(ctx context.Context, parameters BiteBody, tenant *tenantProfile) (BiteResponse, *maps.LatLng, error) {
+	cursor, ok := decodePageCursor(parameters.PageToken)
+	if !ok && parameters.PageSize == 0 {
+		resp, err := fetchNextPage(ctx, parameters.PageToken)
+		if err != nil {
+			return BiteResponse{}, nil, err
+		}
+		biteArray := newBiteResponse(resp, providerGoogle)
+		filterClosures(&biteArray, parameters.IncludeClosed, parameters.ExcludeTemporarilyClosed)
+		return biteArray, nil, nil
+	}
+	if !ok {
+		cursor = pageCursor{Upstream: parameters.PageToken, Size: parameters.PageSize}
+	}
+	biteArray, outcome, err := cursorPage(ctx, cursor, tenant)
+	if err != nil {
+		return BiteResponse{}, nil, err
+	}
+	filterClosures(&biteArray, parameters.IncludeClosed, parameters.ExcludeTemporarilyClosed)
+	biteArray = paginate(biteArray, cursor)
+	biteArray.Meta = pageDebug(biteArray.Meta, parameters.Debug, outcome)
+	var origin *maps.LatLng
+	if cursor.Query != nil {
+		origin = &maps.LatLng{Lat: cursor.Query.Lat, Lng: cursor.Query.Long}
+	}
+	return biteArray, origin, nil
+}
+
+func respondBites(biteArray BiteResponse, exportFormat string) (events.APIGatewayProxyResponse, error) {
+	if exportFormat == exportFormatGeoJSON {
+		return geoJSONSuccess(biteArray)
+	}
+	return clientSuccess(biteArray), nil
 }
 
 func handlePhoto(photoref string) (events.APIGatewayProxyResponse, error) {
 	if len(photoref) > 0 {
-		photoResponse := respondPhoto(photoref)
-		buf := new(bytes.Buffer)
-		buf.ReadFrom(photoResponse.Data)
-		err := photoResponse.Data.Close()
-		check(err)
-		encodedPhoto := base64.StdEncoding.EncodeToString([]byte(buf.String()))
+		ctx := context.Background()
+		key := cacheKey("photo", photoref)
+		var photo []byte
+		if !photoCache.get(ctx, key, &photo) {
+			photoResponse := respondPhoto(photoref)
+			if photoResponse.Data == nil {
+				return clientError(http.StatusNotFound)
+			}
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(photoResponse.Data)
+			err := photoResponse.Data.Close()
+			check(err)
+			photo = moderatePhoto(ctx, photoref, buf.Bytes())
+			photoCache.set(ctx, key, photo)
+		}
+		encodedPhoto := base64.StdEncoding.EncodeToString(photo)
 		return events.APIGatewayProxyResponse{
 			StatusCode:      200,
 			Headers:         map[string]string{"Content-Type": "application/json", "Access-Control-Allow-Origin": "*"},
@@ -121,7 +573,20 @@ func clientError(status int) (events.APIGatewayProxyResponse, error) {
 	}, nil
 }
 
-func clientSuccess(biteArray maps.PlacesSearchResponse) events.APIGatewayProxyResponse {
+func jsonSuccess(v interface{}) (events.APIGatewayProxyResponse, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return serverError(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode:      http.StatusOK,
+		Headers:         map[string]string{"Content-Type": "application/json", "Access-Control-Allow-Origin": "*"},
+		IsBase64Encoded: false,
+		Body:            string(body),
+	}, nil
+}
+
+func clientSuccess(biteArray BiteResponse) events.APIGatewayProxyResponse {
 	jsonBiteArray, err := json.Marshal(biteArray)
 	check(err)
 	return events.APIGatewayProxyResponse{
@@ -132,41 +597,79 @@ func clientSuccess(biteArray maps.PlacesSearchResponse) events.APIGatewayProxyRe
 	}
 }
 
-func respondBiteArray(lat float64, long float64, radius uint, minPrice int, maxPrice int) maps.PlacesSearchResponse {
+func newBiteResponse(resp maps.PlacesSearchResponse, source string) BiteResponse {
+	biteArray := BiteResponse{
+		Results:          make([]BiteResult, 0, len(resp.Results)),
+		HTMLAttributions: resp.HTMLAttributions,
+		NextPageToken:    resp.NextPageToken,
+	}
+	for _, result := range resp.Results {
+		biteResult := BiteResult{
+			PlacesSearchResult: result,
+			Sources:            []string{source},
+			SourceRatings:      map[string]SourceRating{source: newSourceRating(source, result.Rating, result.UserRatingsTotal)},
+		}
+		normalizeRatings(&biteResult)
+		biteArray.Results = append(biteArray.Results, biteResult)
+	}
+	return biteArray
+}
+
+func respondBiteArray(ctx context.Context, lat float64, long float64, radius uint, minPrice int, maxPrice int, keyword string, placeType maps.PlaceType, openNow bool) (maps.PlacesSearchResponse, error) {
 	var client *maps.Client
 	var err error
-	client, err = maps.NewClient(maps.WithAPIKey(apiKey))
-	check(err)
+	client, err = googleClient()
+	if err != nil {
+		return maps.PlacesSearchResponse{}, err
+	}
 	r := &maps.NearbySearchRequest{
 		Radius:  radius,
-		Type:    maps.PlaceTypeRestaurant,
-		OpenNow: true,
+		Type:    placeType,
+		OpenNow: openNow,
+		Keyword: keyword,
 	}
 	parseLocation(fmt.Sprintf("%f,%f", lat, long), r)
 	parsePriceLevels(minPrice, maxPrice, r)
-	resp, err := client.NearbySearch(context.Background(), r)
-	check(err)
+	resp, err := client.NearbySearch(ctx, r)
 	log.Println(resp)
-	return resp
+	return resp, err
 }
 
 func respondNextPage(pagetoken string) maps.PlacesSearchResponse {
-	var client *maps.Client
-	var err error
-	client, err = maps.NewClient(maps.WithAPIKey(apiKey))
+	resp, err := fetchNextPage(context.Background(), pagetoken)
 	check(err)
+	return resp
+}
+
+func fetchNextPage(ctx context.Context, pagetoken string) (maps.PlacesSearchResponse, error) {
+	client, err := googleClient()
+	if err != nil {
+		return maps.PlacesSearchResponse{}, err
+	}
 	r := &maps.NearbySearchRequest{
 		PageToken: pagetoken,
 	}
-	resp, err := client.NearbySearch(context.Background(), r)
-	check(err)
-	return resp
+	return client.NearbySearch(ctx, r)
 }
 
+// photoCache holds photos after moderation, so a cached photo is never one
+// that should have been replaced.
+var photoCache = newLayeredCache("photo", 24*time.Hour)
+
 func respondPhoto(photoref string) maps.PlacePhotoResponse {
+	if strings.HasPrefix(photoref, fixturePhotoPrefix) {
+		resp, err := fixturePhoto(photoref)
+		check(err)
+		return resp
+	}
+	if strings.HasPrefix(photoref, foursquarePhotoPrefix) {
+		resp, err := foursquarePhoto(photoref)
+		check(err)
+		return resp
+	}
 	var client *maps.Client
 	var err error
-	client, err = maps.NewClient(maps.WithAPIKey(apiKey))
+	client, err = googleClient()
 	check(err)
 	r := &maps.PlacePhotoRequest{
 		PhotoReference: photoref,
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image/png"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+const (
+	defaultMapMarkers = 10
+	maxMapMarkers     = 35
+	markerLabels      = "123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// handleMapImage draws the results on a static map. Under privacy the user's
+// own marker is left off, and the snapped location only keeps the map framed.
+func handleMapImage(ctx context.Context, tenant *tenantProfile, lat, long float64, radius uint, minPrice, maxPrice, count int, privacy bool) (events.APIGatewayProxyResponse, error) {
+	if count <= 0 {
+		count = defaultMapMarkers
+	}
+	if count > maxMapMarkers {
+		return clientError(http.StatusBadRequest)
+	}
+	params := searchParams{Lat: lat, Long: long, Radius: radius, MinPrice: minPrice, MaxPrice: maxPrice}
+	biteArray, err := searchPlaces(ctx, params, providersFor(tenant))
+	if err != nil {
+		return serverError(err)
+	}
+	var client *maps.Client
+	client, err = googleClient()
+	if err != nil {
+		return serverError(err)
+	}
+	r := &maps.StaticMapRequest{
+		Size:    "640x640",
+		Scale:   2,
+		Format:  maps.PNG8,
+		MapType: maps.RoadMap,
+	}
+	if privacy {
+		r.Visible = []maps.LatLng{{Lat: lat, Lng: long}}
+	} else {
+		r.Markers = append(r.Markers, maps.Marker{
+			Color:    "blue",
+			Label:    "U",
+			Location: []maps.LatLng{{Lat: lat, Lng: long}},
+		})
+	}
+	for i, result := range biteArray.Results {
+		if i == count {
+			break
+		}
+		r.Markers = append(r.Markers, maps.Marker{
+			Color:    "red",
+			Label:    string(markerLabels[i]),
+			Location: []maps.LatLng{result.Geometry.Location},
+		})
+	}
+	img, err := client.StaticMap(ctx, r)
+	if err != nil {
+		return serverError(err)
+	}
+	buf := new(bytes.Buffer)
+	err = png.Encode(buf, img)
+	if err != nil {
+		return serverError(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode:      http.StatusOK,
+		Headers:         map[string]string{"Content-Type": "image/png", "Access-Control-Allow-Origin": "*"},
+		IsBase64Encoded: true,
+		Body:            base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	mealPriceEnrichLimit = 10
+	mealPriceConcurrency = 5
+)
+
+var mealPriceCache = newTTLCache(24 * time.Hour)
+
+var entreeSections = []string{"main", "entree", "entrée", "plate", "dinner", "lunch", "pizza", "burger", "pasta", "bowl", "noodle", "taco", "sandwich"}
+var nonEntreeSections = []string{"drink", "beverage", "wine", "beer", "cocktail", "dessert", "side", "starter", "appetizer", "appetiser", "kid", "extra", "add-on", "sauce"}
+
+type MealPrice struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency,omitempty"`
+	Items    int     `json:"items,omitempty"`
+	Source   string  `json:"source"`
+}
+
+// estimateMealPrice takes the median price of the menu's entrées. Sections
+// that look like mains are preferred; failing that every priced item outside
+// drinks, desserts and sides is used.
+func estimateMealPrice(menu *Menu) *MealPrice {
+	var mains, others []float64
+	for _, section := range menu.Sections {
+		name := strings.ToLower(section.Name)
+		if containsAny(name, nonEntreeSections) {
+			continue
+		}
+		for _, item := range section.Items {
+			if item.Price <= 0 {
+				continue
+			}
+			if containsAny(name, entreeSections) {
+				mains = append(mains, item.Price)
+			} else {
+				others = append(others, item.Price)
+			}
+		}
+	}
+	prices := mains
+	if len(prices) == 0 {
+		prices = others
+	}
+	if len(prices) == 0 {
+		return nil
+	}
+	sort.Float64s(prices)
+	median := prices[len(prices)/2]
+	if len(prices)%2 == 0 {
+		median = (prices[len(prices)/2-1] + median) / 2
+	}
+	return &MealPrice{Amount: math.Round(median*100) / 100, Currency: menu.Currency, Items: len(prices), Source: mealPriceFromMenu}
+}
+
+func containsAny(s string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func mealPriceFor(ctx context.Context, placeID string) *MealPrice {
+	if cached, ok := mealPriceCache.get(placeID); ok {
+		return cached.(*MealPrice)
+	}
+	place, err := respondPlaceDetails(placeID, maps.PlaceDetailsFieldMaskPlaceID, maps.PlaceDetailsFieldMaskWebsite)
+	if err != nil {
+		logDetailsError(err)
+		return nil
+	}
+	return placeMealPrice(ctx, place)
+}
+
+// placeMealPrice is mealPriceFor when the place's website is already known.
+func placeMealPrice(ctx context.Context, place maps.PlaceDetailsResult) *MealPrice {
+	if cached, ok := mealPriceCache.get(place.PlaceID); ok {
+		return cached.(*MealPrice)
+	}
+	var estimate *MealPrice
+	menu, err := lookupMenu(ctx, place)
+	if err == nil && menu != nil {
+		estimate = estimateMealPrice(menu)
+	}
+	mealPriceCache.set(place.PlaceID, estimate)
+	return estimate
+}
+
+// enrichMealPrices attaches estimated meal prices to the first few results.
+// Each lookup costs a details call and a page fetch, so it is opt-in.
+func enrichMealPrices(ctx context.Context, biteArray *BiteResponse) {
+	sem := make(chan struct{}, mealPriceConcurrency)
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		if i == mealPriceEnrichLimit {
+			break
+		}
+		wg.Add(1)
+		go func(result *BiteResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result.EstimatedMealPrice = mealPriceFor(ctx, result.PlaceID)
+		}(&biteArray.Results[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	mealTypeCoffee    = "coffee"
+	mealTypeBakery    = "bakery"
+	mealTypeBreakfast = "breakfast"
+)
+
+// mealTypeDef says how each provider searches for a meal type in place of
+// plain restaurants. Legacy Nearby Search has no breakfast_restaurant type,
+// so breakfast is a restaurant search with a keyword there.
+type mealTypeDef struct {
+	ID                 string
+	Label              string
+	PlaceType          string
+	GoogleType         maps.PlaceType
+	GoogleKeyword      string
+	FoursquareCategory string
+	OSMSelector        string
+}
+
+var mealTypes = []mealTypeDef{
+	{ID: mealTypeCoffee, Label: "Coffee", PlaceType: "cafe", GoogleType: maps.PlaceTypeCafe, FoursquareCategory: "13032", OSMSelector: `["amenity"="cafe"]`},
+	{ID: mealTypeBakery, Label: "Bakery", PlaceType: "bakery", GoogleType: maps.PlaceTypeBakery, FoursquareCategory: "13002", OSMSelector: `["shop"="bakery"]`},
+	{ID: mealTypeBreakfast, Label: "Breakfast", PlaceType: "breakfast_restaurant", GoogleType: maps.PlaceTypeRestaurant, GoogleKeyword: "breakfast", FoursquareCategory: "13028", OSMSelector: `["amenity"~"^(restaurant|cafe)$"]["cuisine"~"breakfast"]`},
+}
+
+var restaurantMealType = mealTypeDef{
+	PlaceType:          "restaurant",
+	GoogleType:         maps.PlaceTypeRestaurant,
+	FoursquareCategory: foursquareFoodCategory,
+	OSMSelector:        `["amenity"="restaurant"]`,
+}
+
+var mealTypeError = &BodyError{Error: fmt.Sprintf("must be one of %s", strings.Join(mealTypeIDs(), ", ")), Field: "mealType"}
+
+func mealTypeIDs() []string {
+	ids := make([]string, len(mealTypes))
+	for i, m := range mealTypes {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+// mealTypeFor returns the definition for id, restaurants for an empty id,
+// and false for anything else.
+func mealTypeFor(id string) (mealTypeDef, bool) {
+	if id == "" {
+		return restaurantMealType, true
+	}
+	for _, m := range mealTypes {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return mealTypeDef{}, false
+}
+
+// googleKeyword adds the meal type's keyword, if it needs one, to the
+// user's.
+func (m mealTypeDef) googleKeyword(keyword string) string {
+	return strings.TrimSpace(m.GoogleKeyword + " " + keyword)
+}
+
+// matchesMealType is the emulated filter for providers that can't search by
+// meal type: the result's types must include it. Breakfast also matches a
+// "breakfast" cuisine type, which is how OSM-style data tags it.
+func matchesMealType(id string, types []string) bool {
+	m, ok := mealTypeFor(id)
+	if !ok {
+		return false
+	}
+	return contains(types, m.PlaceType) || (id == mealTypeBreakfast && contains(types, mealTypeBreakfast))
+}
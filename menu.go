@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+const (
+	menuFetchTimeout = 5 * time.Second
+	menuMaxPageBytes = 2 << 20
+)
+
+var ldJSONPattern = regexp.MustCompile(`(?is)<script[^>]+type=["']application/ld\+json["'][^>]*>(.*?)</script>`)
+
+type Menu struct {
+	PlaceID            string        `json:"placeId"`
+	Source             string        `json:"source"`
+	Currency           string        `json:"currency,omitempty"`
+	Sections           []MenuSection `json:"sections"`
+	EstimatedMealPrice *MealPrice    `json:"estimatedMealPrice,omitempty"`
+}
+
+type MenuSection struct {
+	Name  string     `json:"name"`
+	Items []MenuItem `json:"items"`
+}
+
+type MenuItem struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Price       float64 `json:"price,omitempty"`
+}
+
+// menuProvider returns the menu for a place, or nil if it has none.
+type menuProvider interface {
+	Name() string
+	Menu(ctx context.Context, place maps.PlaceDetailsResult) (*Menu, error)
+}
+
+func menuProviders() []menuProvider {
+	var providers []menuProvider
+	if cfg.MenuPartnerURL != "" {
+		providers = append(providers, partnerMenuProvider{})
+	}
+	return append(providers, schemaOrgMenuProvider{})
+}
+
+func handleMenu(ctx context.Context, placeID string) (events.APIGatewayProxyResponse, error) {
+	if placeID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	place, err := respondPlaceDetails(placeID, maps.PlaceDetailsFieldMaskPlaceID, maps.PlaceDetailsFieldMaskWebsite)
+	if err != nil {
+		return detailsFailure(err)
+	}
+	menu, err := lookupMenu(ctx, place)
+	if err != nil {
+		return serverError(err)
+	}
+	if menu == nil {
+		return clientError(http.StatusNotFound)
+	}
+	menu.EstimatedMealPrice = estimateMealPrice(menu)
+	return jsonSuccess(menu)
+}
+
+func lookupMenu(ctx context.Context, place maps.PlaceDetailsResult) (*Menu, error) {
+	for _, provider := range menuProviders() {
+		menu, err := provider.Menu(ctx, place)
+		if err != nil {
+			errorLogger.Printf("menu provider %s: %s", provider.Name(), err)
+			continue
+		}
+		if menu != nil {
+			menu.PlaceID = place.PlaceID
+			menu.Source = provider.Name()
+			return menu, nil
+		}
+	}
+	return nil, nil
+}
+
+type partnerMenuProvider struct{}
+
+func (partnerMenuProvider) Name() string {
+	return "partner"
+}
+
+func (partnerMenuProvider) Menu(ctx context.Context, place maps.PlaceDetailsResult) (*Menu, error) {
+	ctx, cancel := context.WithTimeout(ctx, menuFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.MenuPartnerURL+"?placeId="+url.QueryEscape(place.PlaceID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.MenuPartnerKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("menu partner: %s", resp.Status)
+	}
+	var menu Menu
+	err = json.NewDecoder(resp.Body).Decode(&menu)
+	if err != nil || len(menu.Sections) == 0 {
+		return nil, err
+	}
+	return &menu, nil
+}
+
+// schemaOrgMenuProvider reads schema.org Menu markup embedded as JSON-LD in
+// the restaurant's own website.
+type schemaOrgMenuProvider struct{}
+
+func (schemaOrgMenuProvider) Name() string {
+	return "schema.org"
+}
+
+func (schemaOrgMenuProvider) Menu(ctx context.Context, place maps.PlaceDetailsResult) (*Menu, error) {
+	if place.Website == "" {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, menuFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, place.Website, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, menuMaxPageBytes))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range ldJSONPattern.FindAllSubmatch(page, -1) {
+		var doc interface{}
+		if json.Unmarshal(match[1], &doc) != nil {
+			continue
+		}
+		if menu := findSchemaMenu(doc); menu != nil {
+			return menu, nil
+		}
+	}
+	return nil, nil
+}
+
+// findSchemaMenu walks a JSON-LD document looking for the first node typed
+// Menu, including menus nested under a Restaurant's hasMenu or an @graph.
+func findSchemaMenu(node interface{}) *Menu {
+	switch v := node.(type) {
+	case []interface{}:
+		for _, child := range v {
+			if menu := findSchemaMenu(child); menu != nil {
+				return menu
+			}
+		}
+	case map[string]interface{}:
+		if isSchemaMenu(v) {
+			return parseSchemaMenu(v)
+		}
+		for _, key := range []string{"@graph", "hasMenu"} {
+			if menu := findSchemaMenu(v[key]); menu != nil {
+				return menu
+			}
+		}
+	}
+	return nil
+}
+
+func parseSchemaMenu(node map[string]interface{}) *Menu {
+	menu := &Menu{}
+	if items := parseSchemaItems(node["hasMenuItem"], &menu.Currency); len(items) > 0 {
+		menu.Sections = append(menu.Sections, MenuSection{Name: schemaString(node["name"]), Items: items})
+	}
+	for _, section := range schemaList(node["hasMenuSection"]) {
+		s, ok := section.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		items := parseSchemaItems(s["hasMenuItem"], &menu.Currency)
+		if len(items) > 0 {
+			menu.Sections = append(menu.Sections, MenuSection{Name: schemaString(s["name"]), Items: items})
+		}
+	}
+	if len(menu.Sections) == 0 {
+		return nil
+	}
+	return menu
+}
+
+func parseSchemaItems(node interface{}, currency *string) []MenuItem {
+	var items []MenuItem
+	for _, item := range schemaList(node) {
+		i, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		menuItem := MenuItem{
+			Name:        schemaString(i["name"]),
+			Description: schemaString(i["description"]),
+		}
+		for _, offer := range schemaList(i["offers"]) {
+			if o, ok := offer.(map[string]interface{}); ok {
+				menuItem.Price = schemaPrice(o["price"])
+				if c := schemaString(o["priceCurrency"]); c != "" && *currency == "" {
+					*currency = c
+				}
+				break
+			}
+		}
+		if menuItem.Name != "" {
+			items = append(items, menuItem)
+		}
+	}
+	return items
+}
+
+func isSchemaMenu(node map[string]interface{}) bool {
+	for _, t := range schemaList(node["@type"]) {
+		if s, ok := t.(string); ok && strings.TrimPrefix(s, "schema:") == "Menu" {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaList(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+func schemaString(node interface{}) string {
+	s, _ := node.(string)
+	return strings.TrimSpace(s)
+}
+
+func schemaPrice(node interface{}) float64 {
+	switch v := node.(type) {
+	case float64:
+		return v
+	case string:
+		price, _ := strconv.ParseFloat(strings.Trim(v, "$€£ "), 64)
+		return price
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rekognition"
+)
+
+const (
+	moderationRekognition = "rekognition"
+	// rekognitionMaxBytes is the largest image DetectModerationLabels accepts
+	// inline.
+	rekognitionMaxBytes = 5 * 1024 * 1024
+)
+
+// photoModerator decides whether an image is fit to serve. Labels explain a
+// block for the logs.
+type photoModerator interface {
+	Moderate(ctx context.Context, img []byte) (blocked bool, labels []string, err error)
+}
+
+var moderationCache = newTTLCache(7 * 24 * time.Hour)
+
+var placeholder struct {
+	once sync.Once
+	png  []byte
+}
+
+type rekognitionModerator struct {
+	client        *rekognition.Rekognition
+	minConfidence float64
+}
+
+func (m rekognitionModerator) Moderate(ctx context.Context, img []byte) (bool, []string, error) {
+	if len(img) > rekognitionMaxBytes {
+		return false, nil, nil
+	}
+	out, err := m.client.DetectModerationLabelsWithContext(ctx, &rekognition.DetectModerationLabelsInput{
+		Image:         &rekognition.Image{Bytes: img},
+		MinConfidence: aws.Float64(m.minConfidence),
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	var labels []string
+	for _, label := range out.ModerationLabels {
+		labels = append(labels, aws.StringValue(label.Name))
+	}
+	return len(labels) > 0, labels, nil
+}
+
+var moderator struct {
+	once sync.Once
+	photoModerator
+}
+
+// configuredModerator builds the PHOTO_MODERATION moderator on first use, or
+// returns nil when moderation is off.
+func configuredModerator() photoModerator {
+	moderator.once.Do(func() {
+		if cfg.PhotoModeration == moderationRekognition {
+			moderator.photoModerator = rekognitionModerator{
+				client:        rekognition.New(awsSession),
+				minConfidence: cfg.PhotoModerationMinConfidence,
+			}
+		}
+	})
+	return moderator.photoModerator
+}
+
+// moderatePhoto returns the image to serve for photoref: the original, or a
+// placeholder when the moderator flags it. Verdicts are cached per photo.
+// Moderation errors fail open so an outage doesn't blank every photo.
+func moderatePhoto(ctx context.Context, photoref string, img []byte) []byte {
+	moderator := configuredModerator()
+	if moderator == nil {
+		return img
+	}
+	if blocked, ok := moderationCache.get(photoref); ok {
+		if blocked.(bool) {
+			return placeholderImage()
+		}
+		return img
+	}
+	blocked, labels, err := moderator.Moderate(ctx, img)
+	if err != nil {
+		errorLogger.Println(err)
+		return img
+	}
+	moderationCache.set(photoref, blocked)
+	if blocked {
+		errorLogger.Printf("photo blocked by moderation: %v", labels)
+		return placeholderImage()
+	}
+	return img
+}
+
+// placeholderImage is a neutral grey 4:3 PNG served in place of a blocked
+// photo.
+func placeholderImage() []byte {
+	placeholder.once.Do(func() {
+		img := image.NewGray(image.Rect(0, 0, 400, 300))
+		for i := range img.Pix {
+			img.Pix[i] = color.Gray{Y: 0xdd}.Y
+		}
+		buf := new(bytes.Buffer)
+		check(png.Encode(buf, img))
+		placeholder.png = buf.Bytes()
+	})
+	return placeholder.png
+}
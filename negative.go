@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// negativeEntry is a search that found nothing, or that the provider
+// rejected outright. Invalid holds the rejection and is empty for an empty
+// result.
+type negativeEntry struct {
+	Invalid string `json:"invalid,omitempty"`
+}
+
+var negativeResults struct {
+	once  sync.Once
+	cache *layeredCache
+}
+
+func negativeCache() *layeredCache {
+	negativeResults.once.Do(func() {
+		negativeResults.cache = newLayeredCache("negative", cfg.NegativeCacheTTL)
+	})
+	return negativeResults.cache
+}
+
+// isInvalidRequest reports whether the provider rejected the request itself,
+// which retrying won't fix and which says nothing about the provider's
+// health. The maps client reports it as "maps: INVALID_REQUEST - ...".
+func isInvalidRequest(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "INVALID_REQUEST")
+}
+
+// cachedNegative returns the remembered empty or rejected result for a
+// search, so a client retrying an empty area doesn't reach the provider.
+func cachedNegative(ctx context.Context, p placesProvider, params searchParams) (providerResult, bool) {
+	if cfg.NegativeCacheTTL <= 0 {
+		return providerResult{}, false
+	}
+	var entry negativeEntry
+	if !negativeCache().get(ctx, searchKey("negative", p.Name(), params), &entry) {
+		return providerResult{}, false
+	}
+	r := providerResult{name: p.Name()}
+	if entry.Invalid != "" {
+		r.err = errors.New(entry.Invalid)
+	} else {
+		r.filters = applyCapabilities(p.Capabilities(), params, &r.resp)
+	}
+	return r, true
+}
+
+// rememberNegative caches ZERO_RESULTS, which the maps client returns as an
+// empty response, and INVALID_REQUEST errors.
+func rememberNegative(ctx context.Context, r providerResult, params searchParams) {
+	if cfg.NegativeCacheTTL <= 0 {
+		return
+	}
+	switch {
+	case isInvalidRequest(r.err):
+		negativeCache().set(ctx, searchKey("negative", r.name, params), negativeEntry{Invalid: r.err.Error()})
+	case r.err == nil && len(r.resp.Results) == 0:
+		negativeCache().set(ctx, searchKey("negative", r.name, params), negativeEntry{})
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	providerOSM      = "osm"
+	osmIDPrefix      = "osm:"
+	defaultOSMRadius = 1500
+	osmResultLimit   = 60
+	osmAttribution   = `© <a href="https://www.openstreetmap.org/copyright">OpenStreetMap</a> contributors`
+)
+
+type osmProvider struct{}
+
+type overpassResponse struct {
+	Elements []overpassElement `json:"elements"`
+}
+
+type overpassElement struct {
+	Type   string            `json:"type"`
+	ID     int64             `json:"id"`
+	Lat    float64           `json:"lat"`
+	Lon    float64           `json:"lon"`
+	Center *maps.LatLng      `json:"center"`
+	Tags   map[string]string `json:"tags"`
+}
+
+func init() {
+	registeredProviders[providerOSM] = osmProvider{}
+}
+
+func (osmProvider) Name() string {
+	return providerOSM
+}
+
+func (osmProvider) Capabilities() providerCapabilities {
+	return osmCapabilities
+}
+
+func (osmProvider) Search(ctx context.Context, params searchParams) (maps.PlacesSearchResponse, error) {
+	radius := params.Radius
+	if radius == 0 {
+		radius = defaultOSMRadius
+	}
+	origin := maps.LatLng{Lat: params.Lat, Lng: params.Long}
+	south, west, north, east := boundingBox(origin, float64(radius))
+	mealType, _ := mealTypeFor(params.MealType)
+	query := fmt.Sprintf(`[out:json][timeout:10];(node%[6]s(%[1]f,%[2]f,%[3]f,%[4]f);way%[6]s(%[1]f,%[2]f,%[3]f,%[4]f););out center %[5]d;`,
+		south, west, north, east, osmResultLimit, mealType.OSMSelector)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.OverpassURL, strings.NewReader(url.Values{"data": {query}}.Encode()))
+	if err != nil {
+		return maps.PlacesSearchResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return maps.PlacesSearchResponse{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return maps.PlacesSearchResponse{}, fmt.Errorf("overpass: %s", resp.Status)
+	}
+	var overpass overpassResponse
+	err = json.NewDecoder(resp.Body).Decode(&overpass)
+	if err != nil {
+		return maps.PlacesSearchResponse{}, err
+	}
+	result := maps.PlacesSearchResponse{HTMLAttributions: []string{osmAttribution}}
+	for _, element := range overpass.Elements {
+		place := osmPlace(element)
+		if place.Name == "" || distanceMeters(origin, place.Geometry.Location) > float64(radius) {
+			continue
+		}
+		result.Results = append(result.Results, place)
+	}
+	return result, nil
+}
+
+func osmPlace(element overpassElement) maps.PlacesSearchResult {
+	location := maps.LatLng{Lat: element.Lat, Lng: element.Lon}
+	if element.Center != nil {
+		location = *element.Center
+	}
+	types := []string{"restaurant"}
+	if element.Tags["amenity"] == "cafe" {
+		types[0] = "cafe"
+	} else if element.Tags["shop"] == "bakery" {
+		types[0] = "bakery"
+	}
+	for _, cuisine := range strings.Split(element.Tags["cuisine"], ";") {
+		if cuisine != "" {
+			types = append(types, strings.TrimSpace(cuisine))
+		}
+	}
+	if element.Tags["wheelchair"] == "yes" {
+		types = append(types, osmWheelchairType)
+	}
+	if element.Tags["drive_through"] == "yes" {
+		types = append(types, osmDriveThroughType)
+	}
+	vicinity := strings.TrimSpace(element.Tags["addr:housenumber"] + " " + element.Tags["addr:street"])
+	if city := element.Tags["addr:city"]; city != "" {
+		if vicinity != "" {
+			vicinity += ", "
+		}
+		vicinity += city
+	}
+	return maps.PlacesSearchResult{
+		Name:     element.Tags["name"],
+		PlaceID:  fmt.Sprintf("%s%s/%d", osmIDPrefix, element.Type, element.ID),
+		Geometry: maps.AddressGeometry{Location: location},
+		Types:    types,
+		Vicinity: vicinity,
+	}
+}
+
+func boundingBox(center maps.LatLng, radiusMeters float64) (south, west, north, east float64) {
+	latDelta := radiusMeters / metersPerDegree
+	longDelta := latDelta / math.Max(math.Cos(center.Lat*math.Pi/180), 0.01)
+	return center.Lat - latDelta, center.Lng - longDelta, center.Lat + latDelta, center.Lng + longDelta
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	maxPageSize      = 20
+	maxUpstreamPages = 3
+	pageCursorPrefix = "bite:"
+	sortByScore      = "score"
+	sortByDistance   = "distance"
+
+	// googleNextPageDelay is how long a Google next_page_token takes to
+	// become valid after it is issued.
+	googleNextPageDelay = 2 * time.Second
+)
+
+var (
+	pageSizeError = &BodyError{Error: "must be between 1 and 20", Field: "pageSize"}
+	sortByError   = &BodyError{Error: "must be score or distance", Field: "sortBy"}
+)
+
+// pageCache holds the full page behind a cursor, so later slices of it are
+// served without another search, on any container when CACHE_L2 is set.
+var pageCache = newLayeredCache("page", 5*time.Minute)
+
+// pageCursor is the continuation token handed out when pageSize is smaller
+// than the page we fetched. It carries enough to rebuild the page on a cold
+// container: either the original create request or the upstream token. With
+// CURSOR_SECRET set, cursors are signed and unsigned ones are rejected.
+type pageCursor struct {
+	Query    *BiteBody `json:"q,omitempty"`
+	Upstream string    `json:"u,omitempty"`
+	Offset   int       `json:"o,omitempty"`
+	Size     int       `json:"n"`
+}
+
+func validPageSize(size int) bool {
+	return size >= 0 && size <= maxPageSize
+}
+
+func validSortBy(sortBy string) bool {
+	return sortBy == "" || sortBy == sortByScore || sortBy == sortByDistance
+}
+
+func (c pageCursor) encode() string {
+	raw, err := json.Marshal(c)
+	check(err)
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	if cfg.CursorSecret == "" {
+		return pageCursorPrefix + payload
+	}
+	return pageCursorPrefix + payload + "." + cursorSignature(payload)
+}
+
+func cursorSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte(cfg.CursorSecret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// key identifies the underlying page, whatever the offset into it.
+func (c pageCursor) key() string {
+	c.Offset = 0
+	return c.encode()
+}
+
+func decodePageCursor(token string) (pageCursor, bool) {
+	var c pageCursor
+	if !strings.HasPrefix(token, pageCursorPrefix) {
+		return c, false
+	}
+	payload := strings.TrimPrefix(token, pageCursorPrefix)
+	if cfg.CursorSecret != "" {
+		var signature string
+		payload, signature, _ = strings.Cut(payload, ".")
+		if !hmac.Equal([]byte(signature), []byte(cursorSignature(payload))) {
+			return c, false
+		}
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || json.Unmarshal(raw, &c) != nil {
+		return c, false
+	}
+	if c.Size < 1 || c.Size > maxPageSize || c.Offset < 0 {
+		return c, false
+	}
+	// An unsigned cursor's query came from the client, so it gets the same
+	// checks as a create request; debug output stays admin only.
+	if c.Query != nil && (c.Query.Debug || validateCreate(*c.Query) != nil) {
+		return c, false
+	}
+	return c, true
+}
+
+// cursorPage returns the full page a cursor slices, from pageCache or by
+// repeating the search, along with which of the two it was.
+func cursorPage(ctx context.Context, c pageCursor, tenant *tenantProfile) (BiteResponse, string, error) {
+	key := c.key()
+	var page BiteResponse
+	if pageCache.get(ctx, key, &page) {
+		return page, cacheHit, nil
+	}
+	if c.Query != nil {
+		var err error
+		page, err = searchCreate(ctx, *c.Query, tenant)
+		if err != nil {
+			return BiteResponse{}, "", err
+		}
+	} else {
+		resp, err := fetchNextPage(ctx, c.Upstream)
+		if err != nil {
+			return BiteResponse{}, "", err
+		}
+		page = newBiteResponse(resp, providerGoogle)
+	}
+	pageCache.set(ctx, key, page)
+	return page, cacheMiss, nil
+}
+
+// paginate cuts c.Size results out of page at c.Offset. While the page has
+// more, NextPageToken is a cursor further into it; after that it wraps the
+// upstream token so the following page is sliced the same way.
+func paginate(page BiteResponse, c pageCursor) BiteResponse {
+	start := c.Offset
+	if start > len(page.Results) {
+		start = len(page.Results)
+	}
+	end := start + c.Size
+	if end > len(page.Results) {
+		end = len(page.Results)
+	}
+	sliced := page
+	sliced.Results = page.Results[start:end]
+	sliced.NextPageToken = ""
+	if end < len(page.Results) {
+		next := c
+		next.Offset = end
+		sliced.NextPageToken = next.encode()
+	} else if page.NextPageToken != "" {
+		sliced.NextPageToken = pageCursor{Upstream: page.NextPageToken, Size: c.Size}.encode()
+	}
+	return sliced
+}
+
+// aggregatePages follows upstream page tokens so that sorting and filtering
+// see the whole result set instead of one page at a time, which would leave
+// duplicates and gaps across pages.
+func aggregatePages(ctx context.Context, biteArray *BiteResponse) {
+	for pages := 1; biteArray.NextPageToken != "" && pages < maxUpstreamPages; pages++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(googleNextPageDelay):
+		}
+		next := newBiteResponse(respondNextPage(biteArray.NextPageToken), providerGoogle)
+		biteArray.Results = append(biteArray.Results, next.Results...)
+		biteArray.HTMLAttributions = append(biteArray.HTMLAttributions, next.HTMLAttributions...)
+		biteArray.NextPageToken = next.NextPageToken
+	}
+	biteArray.NextPageToken = ""
+}
+
+// sortResults orders the aggregated set with place ID as the tiebreak, so a
+// rebuilt page on another container slices identically.
+func sortResults(biteArray *BiteResponse, sortBy string, params searchParams) {
+	center := maps.LatLng{Lat: params.Lat, Lng: params.Long}
+	key := func(r BiteResult) float64 {
+		if sortBy == sortByDistance {
+			return distanceMeters(center, r.Geometry.Location)
+		}
+		return -float64(r.Score)
+	}
+	sort.SliceStable(biteArray.Results, func(i, j int) bool {
+		a, b := biteArray.Results[i], biteArray.Results[j]
+		if ka, kb := key(a), key(b); ka != kb {
+			return ka < kb
+		}
+		return a.PlaceID < b.PlaceID
+	})
+}
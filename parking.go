@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	parkingRadius      = 150
+	parkingPerResult   = 3
+	parkingConcurrency = 5
+)
+
+var parkingCache = newTTLCache(7 * 24 * time.Hour)
+
+type ParkingOption struct {
+	Name           string      `json:"name"`
+	PlaceID        string      `json:"placeId"`
+	Location       maps.LatLng `json:"location"`
+	DistanceMeters int         `json:"distanceMeters"`
+}
+
+func respondParking(ctx context.Context, location maps.LatLng) ([]ParkingOption, error) {
+	var client *maps.Client
+	var err error
+	client, err = googleClient()
+	if err != nil {
+		return nil, err
+	}
+	r := &maps.NearbySearchRequest{
+		Location: &location,
+		Radius:   parkingRadius,
+		Type:     maps.PlaceTypeParking,
+	}
+	resp, err := client.NearbySearch(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	options := []ParkingOption{}
+	for _, result := range resp.Results {
+		options = append(options, ParkingOption{
+			Name:           result.Name,
+			PlaceID:        result.PlaceID,
+			Location:       result.Geometry.Location,
+			DistanceMeters: int(math.Round(distanceMeters(location, result.Geometry.Location))),
+		})
+	}
+	sort.Slice(options, func(i, j int) bool {
+		return options[i].DistanceMeters < options[j].DistanceMeters
+	})
+	if len(options) > parkingPerResult {
+		options = options[:parkingPerResult]
+	}
+	return options, nil
+}
+
+// enrichParking attaches the closest parking to each result. Parking barely
+// changes, so lookups are cached for a week per place.
+func enrichParking(ctx context.Context, biteArray *BiteResponse) {
+	sem := make(chan struct{}, parkingConcurrency)
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		wg.Add(1)
+		go func(result *BiteResult) {
+			defer wg.Done()
+			if cached, ok := parkingCache.get(result.PlaceID); ok {
+				result.Parking = cached.([]ParkingOption)
+				return
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			options, err := respondParking(ctx, result.Geometry.Location)
+			if err != nil {
+				errorLogger.Println(err)
+				return
+			}
+			parkingCache.set(result.PlaceID, options)
+			result.Parking = options
+		}(&biteArray.Results[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const redacted = "[redacted]"
+
+var payloadLogger = log.New(os.Stdout, "PAYLOAD ", 0)
+
+// redactedFields are JSON keys whose values are never logged, compared after
+// lowercasing and dropping underscores: anything that locates a user or could
+// be replayed as a credential.
+var redactedFields = map[string]bool{
+	"lat":              true,
+	"long":             true,
+	"lng":              true,
+	"latitude":         true,
+	"longitude":        true,
+	"location":         true,
+	"geometry":         true,
+	"viewport":         true,
+	"pagetoken":        true,
+	"nextpagetoken":    true,
+	"photoref":         true,
+	"photoreference":   true,
+	"token":            true,
+	"cursor":           true,
+	"keyid":            true,
+	"apikey":           true,
+	"key":              true,
+	"secret":           true,
+	"signature":        true,
+	"authorization":    true,
+	"xapikey":          true,
+	"x-api-key":        true,
+	"x-bite-signature": true,
+	"x-bite-nonce":     true,
+	"cookie":           true,
+}
+
+type payloadLog struct {
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Query      map[string]string `json:"query,omitempty"`
+	Request    interface{}       `json:"request,omitempty"`
+	Status     int               `json:"status"`
+	Response   interface{}       `json:"response,omitempty"`
+	DurationMs int64             `json:"durationMs"`
+}
+
+// logPayloads wraps a handler so that a PAYLOAD_LOG_SAMPLE_RATE fraction of
+// invocations log their full request and response with coordinates and
+// tokens redacted.
+func logPayloads(next func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)) func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if cfg.PayloadLogSampleRate <= 0 || rand.Float64() >= cfg.PayloadLogSampleRate {
+			return next(ctx, req)
+		}
+		start := time.Now()
+		resp, err := next(ctx, req)
+		entry := payloadLog{
+			Method:     req.HTTPMethod,
+			Path:       redactPath(req.Path),
+			Headers:    redactStrings(req.Headers),
+			Query:      redactStrings(req.QueryStringParameters),
+			Request:    redactBody(req.Body),
+			Status:     resp.StatusCode,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if strings.HasPrefix(resp.Headers["Content-Type"], "application/json") {
+			entry.Response = redactBody(resp.Body)
+		}
+		line, merr := json.Marshal(entry)
+		if merr == nil {
+			payloadLogger.Println(string(line))
+		}
+		return resp, err
+	}
+}
+
+func isRedactedField(name string) bool {
+	name = strings.ToLower(name)
+	return redactedFields[name] || redactedFields[strings.ReplaceAll(name, "_", "")]
+}
+
+// redactPath hides the signed token in share links.
+func redactPath(path string) string {
+	if strings.HasPrefix(path, "/share/") {
+		return "/share/" + redacted
+	}
+	return path
+}
+
+func redactStrings(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(values))
+	for k, v := range values {
+		if isRedactedField(k) {
+			v = redacted
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactBody parses a JSON body and redacts it; bodies that are not JSON or
+// exceed PAYLOAD_LOG_MAX_BYTES are summarised by size only.
+func redactBody(body string) interface{} {
+	if body == "" {
+		return nil
+	}
+	if len(body) > cfg.PayloadLogMaxBytes {
+		return map[string]int{"truncatedBytes": len(body)}
+	}
+	var v interface{}
+	if json.Unmarshal([]byte(body), &v) != nil {
+		return map[string]int{"nonJSONBytes": len(body)}
+	}
+	return redactValue(v)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if isRedactedField(k) {
+				v[k] = redacted
+			} else {
+				v[k] = redactValue(child)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactValue(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
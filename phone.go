@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// PhoneNumber is a place's number in E.164 for tap-to-call, plus a display
+// form: national format for callers in the same country, international
+// otherwise.
+type PhoneNumber struct {
+	E164    string `json:"e164"`
+	Display string `json:"display"`
+	Region  string `json:"region,omitempty"`
+}
+
+// normalizePhone parses Google's international_phone_number, which always
+// carries a country code. Numbers that don't validate are dropped rather than
+// handed to a dialer.
+func normalizePhone(international string, locale displayLocale) *PhoneNumber {
+	if international == "" {
+		return nil
+	}
+	number, err := phonenumbers.Parse(international, "")
+	if err != nil || !phonenumbers.IsValidNumber(number) {
+		return nil
+	}
+	region := phonenumbers.GetRegionCodeForNumber(number)
+	format := phonenumbers.INTERNATIONAL
+	if _, callerRegion, _ := strings.Cut(locale.Tag, "-"); callerRegion == region {
+		format = phonenumbers.NATIONAL
+	}
+	return &PhoneNumber{
+		E164:    phonenumbers.Format(number, phonenumbers.E164),
+		Display: phonenumbers.Format(number, format),
+		Region:  region,
+	}
+}
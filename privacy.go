@@ -0,0 +1,33 @@
+package main
+
+import "math"
+
+const (
+	privacyGridMeters = 100
+	metersPerDegree   = 111320.0
+)
+
+type PrivacyMeta struct {
+	GridMeters      int    `json:"gridMeters"`
+	MaxOffsetMeters int    `json:"maxOffsetMeters"`
+	Note            string `json:"note"`
+}
+
+// snapToGrid rounds a coordinate to the centre of its ~100m grid cell. The
+// longitude step widens with latitude so cells stay roughly square.
+func snapToGrid(lat, long float64) (float64, float64) {
+	latStep := privacyGridMeters / metersPerDegree
+	snappedLat := (math.Floor(lat/latStep) + 0.5) * latStep
+	longStep := latStep / math.Max(math.Cos(snappedLat*math.Pi/180), 0.01)
+	snappedLong := (math.Floor(long/longStep) + 0.5) * longStep
+	return snappedLat, snappedLong
+}
+
+func privacyMeta() *PrivacyMeta {
+	maxOffset := int(math.Ceil(privacyGridMeters * math.Sqrt2 / 2))
+	return &PrivacyMeta{
+		GridMeters:      privacyGridMeters,
+		MaxOffsetMeters: maxOffset,
+		Note:            "Search location was snapped to a 100m grid, so distances and the radius edge may be off by up to the max offset.",
+	}
+}
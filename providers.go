@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	providerGoogle = "google"
+	dedupMeters    = 75
+)
+
+type searchParams struct {
+	Lat      float64
+	Long     float64
+	Radius   uint
+	MinPrice int
+	MaxPrice int
+	Keyword  string
+	MealType string
+	// IgnoreOpenNow searches places whether or not they are open right now.
+	IgnoreOpenNow bool
+}
+
+type placesProvider interface {
+	Name() string
+	Capabilities() providerCapabilities
+	Search(ctx context.Context, params searchParams) (maps.PlacesSearchResponse, error)
+}
+
+type googleProvider struct{}
+
+func (googleProvider) Name() string {
+	return providerGoogle
+}
+
+func (googleProvider) Capabilities() providerCapabilities {
+	return googleCapabilities
+}
+
+func (googleProvider) Search(ctx context.Context, params searchParams) (maps.PlacesSearchResponse, error) {
+	mealType, _ := mealTypeFor(params.MealType)
+	return respondBiteArray(ctx, params.Lat, params.Long, params.Radius, params.MinPrice, params.MaxPrice, mealType.googleKeyword(params.Keyword), mealType.GoogleType, !params.IgnoreOpenNow)
+}
+
+var registeredProviders = map[string]placesProvider{
+	providerGoogle: googleProvider{},
+}
+
+// providersFor resolves the providers to query, preferring the tenant's
+// provider over the PROVIDERS env var and falling back to Google.
+func providersFor(tenant *tenantProfile) []placesProvider {
+	names := cfg.Providers
+	if tenant != nil && tenant.Provider != "" {
+		names = strings.Split(tenant.Provider, ",")
+	}
+	var active []placesProvider
+	for _, name := range names {
+		if p, ok := registeredProviders[strings.TrimSpace(name)]; ok {
+			active = append(active, p)
+		}
+	}
+	if len(active) == 0 {
+		active = append(active, registeredProviders[providerGoogle])
+	}
+	return active
+}
+
+func searchPlaces(ctx context.Context, params searchParams, providers []placesProvider) (BiteResponse, error) {
+	if len(providers) == 1 {
+		r := searchProvider(ctx, providers[0], params)
+		if r.err != nil {
+			return BiteResponse{}, r.err
+		}
+		biteArray := newBiteResponse(r.resp, r.name)
+		biteArray.Meta = &ResponseMeta{
+			Filters: map[string]map[string]string{r.name: r.filters},
+			timings: []ProviderTiming{r.timing},
+		}
+		biteArray.Meta.markStale(r.dataAsOf)
+		return biteArray, nil
+	}
+	return fanOut(ctx, params, providers)
+}
+
+type providerResult struct {
+	name    string
+	resp    maps.PlacesSearchResponse
+	filters map[string]string
+	timing  ProviderTiming
+	err     error
+	// dataAsOf is set when resp is a stale copy served during an outage.
+	dataAsOf *time.Time
+}
+
+// searchProvider answers empty and rejected searches from the negative
+// cache and recent ones from the search cache, and otherwise coalesces
+// identical searches into one provider call.
+func searchProvider(ctx context.Context, p placesProvider, params searchParams) providerResult {
+	if r, ok := cachedNegative(ctx, p, params); ok {
+		return r
+	}
+	if r, ok := cachedResults(ctx, p, params); ok {
+		return r
+	}
+	return coalescedSearch(ctx, p, params)
+}
+
+// callProvider calls a provider through its circuit breaker. When the
+// breaker is open or the call fails, the area's last good results stand in
+// if they are recent enough. Empty and rejected searches are remembered
+// briefly and answered without a call.
+func callProvider(ctx context.Context, p placesProvider, params searchParams) providerResult {
+	breaker := breakerFor(p.Name())
+	if !breaker.allow() {
+		r := providerResult{name: p.Name(), err: errCircuitOpen}
+		r.timing = newProviderTiming(r, 0)
+		return staleResult(ctx, r, params)
+	}
+	start := time.Now()
+	resp, err := searchWithFaults(ctx, p, params)
+	r := providerResult{name: p.Name(), resp: resp, err: err}
+	r.timing = newProviderTiming(r, time.Since(start))
+	if isInvalidRequest(err) {
+		breaker.record(nil)
+		rememberNegative(ctx, r, params)
+		return r
+	}
+	breaker.record(err)
+	if err != nil {
+		return staleResult(ctx, r, params)
+	}
+	rememberNegative(ctx, r, params)
+	r.filters = applyCapabilities(p.Capabilities(), params, &r.resp)
+	rememberResults(ctx, r, params)
+	return r
+}
+
+// fanOut queries every provider concurrently under one deadline and merges
+// whatever came back in time. Page tokens are provider specific, so merged
+// responses are not paginated.
+func fanOut(ctx context.Context, params searchParams, providers []placesProvider) (BiteResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.ProviderTimeout)
+	defer cancel()
+	results := make([]providerResult, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p placesProvider) {
+			defer wg.Done()
+			results[i] = searchProvider(ctx, p, params)
+		}(i, p)
+	}
+	wg.Wait()
+	merged := BiteResponse{Meta: &ResponseMeta{Filters: map[string]map[string]string{}}}
+	succeeded := 0
+	for _, r := range results {
+		merged.Meta.timings = append(merged.Meta.timings, r.timing)
+		if r.err != nil {
+			errorLogger.Printf("provider %s: %s", r.name, r.err)
+			continue
+		}
+		succeeded++
+		merged.Meta.markStale(r.dataAsOf)
+		// r.filters may be shared with coalesced searches, so it is copied
+		// rather than marked in place.
+		filters := map[string]string{filterPagination: filterSkipped}
+		for filter, state := range r.filters {
+			if filter != filterPagination {
+				filters[filter] = state
+			}
+		}
+		merged.Meta.Filters[r.name] = filters
+		merged.HTMLAttributions = append(merged.HTMLAttributions, r.resp.HTMLAttributions...)
+		for _, place := range r.resp.Results {
+			mergeResult(&merged, place, r.name)
+		}
+	}
+	if succeeded == 0 {
+		return BiteResponse{}, errors.New("all providers failed")
+	}
+	for i := range merged.Results {
+		normalizeRatings(&merged.Results[i])
+	}
+	sort.SliceStable(merged.Results, func(i, j int) bool {
+		return merged.Results[i].Score > merged.Results[j].Score
+	})
+	return merged, nil
+}
+
+func mergeResult(merged *BiteResponse, place maps.PlacesSearchResult, source string) {
+	for i := range merged.Results {
+		existing := &merged.Results[i]
+		if samePlace(existing.PlacesSearchResult, place) {
+			existing.Sources = append(existing.Sources, source)
+			existing.SourceRatings[source] = newSourceRating(source, place.Rating, place.UserRatingsTotal)
+			return
+		}
+	}
+	merged.Results = append(merged.Results, BiteResult{
+		PlacesSearchResult: place,
+		Sources:            []string{source},
+		SourceRatings:      map[string]SourceRating{source: newSourceRating(source, place.Rating, place.UserRatingsTotal)},
+	})
+}
+
+func samePlace(a, b maps.PlacesSearchResult) bool {
+	name := normalizeName(a.Name)
+	if name == "" || name != normalizeName(b.Name) {
+		return false
+	}
+	return distanceMeters(a.Geometry.Location, b.Geometry.Location) <= dedupMeters
+}
+
+// normalizeName reduces a name to its letters and digits, in any script,
+// without a leading "The", so providers' spellings of one place compare equal.
+func normalizeName(name string) string {
+	name = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "the ")
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func distanceMeters(a, b maps.LatLng) float64 {
+	const earthRadius = 6371000.0
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
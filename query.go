@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	parserRules = "rules"
+	parserLLM   = "llm"
+
+	walkingMeters      = 800
+	nearbyMeters       = 1500
+	walkMetersPerMin   = 80
+	driveMetersPerMin  = 500
+	llmFallbackMinWord = 4
+	queryParsePrompt   = `Turn this restaurant search into filters. Reply with only a JSON object with any of: "keyword" (cuisine or dish), "minPrice" and "maxPrice" (1-4), "radiusMeters", "maxPerPerson" (amount in local currency), "mealType" (coffee, bakery or breakfast), "openLate" (boolean).
+
+%s`
+)
+
+var queryParseCache = newTTLCache(24 * time.Hour)
+
+// QueryInterpretation is how a free-text query was read. It is echoed in the
+// response meta so users can see, and correct, the interpretation.
+type QueryInterpretation struct {
+	Query    string `json:"query"`
+	Parser   string `json:"parser"`
+	Keyword  string `json:"keyword,omitempty"`
+	MinPrice int    `json:"minPrice,omitempty"`
+	MaxPrice int    `json:"maxPrice,omitempty"`
+	Radius   uint   `json:"radiusMeters,omitempty"`
+	OpenNow  bool   `json:"openNow,omitempty"`
+	OpenLate bool   `json:"openLate,omitempty"`
+
+	MaxPerPerson float64 `json:"maxPerPerson,omitempty"`
+	MealType     string  `json:"mealType,omitempty"`
+}
+
+type queryPhrase struct {
+	pattern *regexp.Regexp
+	apply   func(q *QueryInterpretation, match []string)
+}
+
+var queryPhrases = []queryPhrase{
+	{regexp.MustCompile(`\bwithin (\d+(?:\.\d+)?) ?(miles?|mi|kilometers?|km|meters?|m|blocks?)\b`), func(q *QueryInterpretation, m []string) {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		unit := map[byte]float64{'m': 1, 'k': 1000, 'b': 100}[m[2][0]]
+		if strings.HasPrefix(m[2], "mi") {
+			unit = 1609.344
+		}
+		q.Radius = uint(n * unit)
+	}},
+	{regexp.MustCompile(`\b(\d+) ?min(?:ute)?s? (walk|drive)\b`), func(q *QueryInterpretation, m []string) {
+		n, _ := strconv.Atoi(m[1])
+		perMinute := walkMetersPerMin
+		if m[2] == "drive" {
+			perMinute = driveMetersPerMin
+		}
+		q.Radius = uint(n * perMinute)
+	}},
+	{regexp.MustCompile(`\b(within )?(walking distance|walkable)\b`), func(q *QueryInterpretation, m []string) { q.Radius = walkingMeters }},
+	{regexp.MustCompile(`\b(nearby|close by|around here)\b`), func(q *QueryInterpretation, m []string) { q.Radius = nearbyMeters }},
+	{regexp.MustCompile(`\b(?:under|less than|below|max) ?[$£€]?(\d+(?:\.\d+)?) ?(?:dollars|bucks|euros|pounds)?(?: ?(?:per person|a head|each|pp))?`), func(q *QueryInterpretation, m []string) {
+		q.MaxPerPerson, _ = strconv.ParseFloat(m[1], 64)
+	}},
+	{regexp.MustCompile(`\b(cheap|inexpensive|budget|cheap eats)\b`), func(q *QueryInterpretation, m []string) { q.MaxPrice = 1 }},
+	{regexp.MustCompile(`\b(affordable|reasonably priced|moderately priced|mid-range)\b`), func(q *QueryInterpretation, m []string) { q.MaxPrice = 2 }},
+	{regexp.MustCompile(`\b(fancy|upscale|expensive|fine dining|splurge|high end)\b`), func(q *QueryInterpretation, m []string) { q.MinPrice = 3 }},
+	{regexp.MustCompile(`\b(coffee|espresso|latte|cappuccino|coffee shop|cafe|café)\b`), func(q *QueryInterpretation, m []string) { q.MealType = mealTypeCoffee }},
+	{regexp.MustCompile(`\b(bakery|bakeries|pastries|pastry|croissants?)\b`), func(q *QueryInterpretation, m []string) { q.MealType = mealTypeBakery }},
+	{regexp.MustCompile(`\b(breakfast|brunch)\b`), func(q *QueryInterpretation, m []string) { q.MealType = mealTypeBreakfast }},
+	{regexp.MustCompile(`\b(open late|late night|late-night|after midnight)\b`), func(q *QueryInterpretation, m []string) { q.OpenLate = true }},
+	{regexp.MustCompile(`\b(open now|open right now)\b`), func(q *QueryInterpretation, m []string) { q.OpenNow = true }},
+}
+
+var queryStopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "some": true, "food": true, "place": true, "places": true,
+	"restaurant": true, "restaurants": true, "with": true, "and": true, "for": true, "me": true,
+	"near": true, "in": true, "to": true, "get": true, "i": true, "want": true, "find": true,
+	"good": true, "best": true, "spot": true, "spots": true, "within": true, "open": true, "of": true,
+	"that": true, "is": true, "are": true, "somewhere": true, "something": true, "eat": true, "lets": true,
+}
+
+// parseQuery reads a free-text search with the phrase rules. When the rules
+// find no filters in a longer query and a model is configured, the model is
+// asked instead.
+func parseQuery(ctx context.Context, text string) QueryInterpretation {
+	q := QueryInterpretation{Query: text, Parser: parserRules}
+	rest := strings.ToLower(text)
+	for _, phrase := range queryPhrases {
+		for _, match := range phrase.pattern.FindAllStringSubmatch(rest, -1) {
+			phrase.apply(&q, match)
+		}
+		rest = phrase.pattern.ReplaceAllString(rest, " ")
+	}
+	var words []string
+	for _, word := range strings.Fields(rest) {
+		word = strings.Trim(word, ".,!?'\"")
+		if word != "" && !queryStopwords[word] {
+			words = append(words, word)
+		}
+	}
+	q.Keyword = strings.Join(words, " ")
+	foundFilters := q.MinPrice > 0 || q.MaxPrice > 0 || q.MaxPerPerson > 0 || q.MealType != "" || q.Radius > 0 || q.OpenLate || q.OpenNow
+	if !foundFilters && len(words) >= llmFallbackMinWord {
+		if parsed, ok := parseQueryWithModel(ctx, text); ok {
+			return parsed
+		}
+	}
+	return q
+}
+
+func parseQueryWithModel(ctx context.Context, text string) (QueryInterpretation, bool) {
+	provider := configuredDigester()
+	if provider == nil {
+		return QueryInterpretation{}, false
+	}
+	if cached, ok := queryParseCache.get(text); ok {
+		return cached.(QueryInterpretation), true
+	}
+	out, err := provider.Complete(ctx, fmt.Sprintf(queryParsePrompt, text))
+	if err != nil {
+		errorLogger.Println(err)
+		return QueryInterpretation{}, false
+	}
+	start, end := strings.Index(out, "{"), strings.LastIndex(out, "}")
+	if start < 0 || end < start {
+		return QueryInterpretation{}, false
+	}
+	var parsed QueryInterpretation
+	if json.Unmarshal([]byte(out[start:end+1]), &parsed) != nil {
+		return QueryInterpretation{}, false
+	}
+	parsed.Query = text
+	parsed.Parser = parserLLM
+	if parsed.MinPrice < 0 || parsed.MinPrice > 4 {
+		parsed.MinPrice = 0
+	}
+	if parsed.MaxPrice < 0 || parsed.MaxPrice > 4 {
+		parsed.MaxPrice = 0
+	}
+	if parsed.MaxPerPerson < 0 {
+		parsed.MaxPerPerson = 0
+	}
+	if _, ok := mealTypeFor(parsed.MealType); !ok {
+		parsed.MealType = ""
+	}
+	queryParseCache.set(text, parsed)
+	return parsed, true
+}
+
+// apply fills the request's structured filters from the interpretation.
+// Filters the client set explicitly win.
+func (q QueryInterpretation) apply(parameters *BiteBody) {
+	if parameters.Keyword == "" {
+		parameters.Keyword = q.Keyword
+	}
+	if parameters.MinPrice == 0 {
+		parameters.MinPrice = q.MinPrice
+	}
+	if parameters.MaxPrice == 0 {
+		parameters.MaxPrice = q.MaxPrice
+	}
+	if parameters.Radius == 0 {
+		parameters.Radius = q.Radius
+	}
+	if q.OpenLate {
+		parameters.LateNight = true
+	}
+	if parameters.MealType == "" {
+		parameters.MealType = q.MealType
+	}
+	if parameters.MaxPerPerson == 0 {
+		parameters.MaxPerPerson = q.MaxPerPerson
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "math"
+
+const (
+	// The prior pulls scores for places with few ratings towards a middling
+	// 70/100 until roughly ratingPriorCount ratings have been seen.
+	ratingPriorMean  = 0.7
+	ratingPriorCount = 20
+)
+
+var ratingScales = map[string]float32{
+	providerGoogle:     5,
+	providerFoursquare: 10,
+	providerFixture:    5,
+}
+
+type SourceRating struct {
+	Rating float32 `json:"rating"`
+	Scale  float32 `json:"scale"`
+	Count  int     `json:"count"`
+}
+
+func newSourceRating(source string, rating float32, count int) SourceRating {
+	return SourceRating{Rating: rating, Scale: ratingScales[source], Count: count}
+}
+
+// normalizeRatings sets the result's Score to a 0–100 confidence-weighted
+// score over every source that rated it, and its Rating to the review-count
+// weighted average on Google's 5-star scale.
+func normalizeRatings(result *BiteResult) {
+	sum, count := ratingEvidence(*result)
+	if count == 0 {
+		result.Rating = 0
+		result.Score = 0
+		return
+	}
+	result.Rating = float32(math.Round(sum/count*5*10) / 10)
+	score := (ratingPriorMean*ratingPriorCount + sum) / (ratingPriorCount + count)
+	result.Score = math.Round(score * 100)
+}
+
+// ratingEvidence sums each source's rating on a 0–1 scale weighted by its
+// review count, returning the weighted sum and the total weight.
+func ratingEvidence(result BiteResult) (sum, count float64) {
+	for _, r := range result.SourceRatings {
+		if r.Rating == 0 || r.Scale == 0 {
+			continue
+		}
+		n := float64(r.Count)
+		if n == 0 {
+			n = 1
+		}
+		sum += float64(r.Rating/r.Scale) * n
+		count += n
+	}
+	return sum, count
+}
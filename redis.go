@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const storeRedis = "redis"
+
+// releaseLeaseScript deletes a lease only while the caller still owns it.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// rateWindowScript counts a request in a window, setting the window's expiry
+// on its first request.
+var rateWindowScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("EXPIRE", KEYS[1], ARGV[1])
+end
+return n`)
+
+var redisConn struct {
+	once   sync.Once
+	client *redis.Client
+}
+
+// redisClient connects lazily to the ElastiCache cluster at REDIS_ADDR. A
+// Lambda container runs one invocation at a time, so the pool only needs to
+// cover that invocation's concurrent lookups; the cluster sees
+// REDIS_POOL_SIZE times the function's concurrency at most. Idle connections
+// are dropped well before a frozen container's sockets go stale.
+func redisClient() *redis.Client {
+	redisConn.once.Do(func() {
+		options := &redis.Options{
+			Addr:            cfg.RedisAddr,
+			Password:        cfg.RedisPassword,
+			PoolSize:        cfg.RedisPoolSize,
+			MinIdleConns:    1,
+			PoolTimeout:     time.Second,
+			ConnMaxIdleTime: time.Minute,
+			DialTimeout:     time.Second,
+			ReadTimeout:     500 * time.Millisecond,
+			WriteTimeout:    500 * time.Millisecond,
+		}
+		if cfg.RedisTLS {
+			options.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		redisConn.client = redis.NewClient(options)
+	})
+	return redisConn.client
+}
+
+// redisCache is the Redis L2, for deployments running inside the VPC.
+type redisCache struct {
+	client *redis.Client
+}
+
+func (r redisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r redisCache) Lease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, owner, ttl).Result()
+}
+
+func (r redisCache) Release(ctx context.Context, key, owner string) error {
+	return releaseLeaseScript.Run(ctx, r.client, []string{key}, owner).Err()
+}
+
+// consumeRedisRateLimit is consumeRateLimit's fixed window kept in Redis.
+func consumeRedisRateLimit(ctx context.Context, keyHash string, window int64, limit int) (bool, error) {
+	n, err := rateWindowScript.Run(ctx, redisClient(), []string{cacheKey("rate", keyHash, window)}, 120).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n <= int64(limit), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// relaxBaseRadius stands in for an unset radius when widening it.
+	relaxBaseRadius  = 1500
+	maxRelaxedRadius = 50000
+	relaxRadiusSteps = 2
+)
+
+var minResultsError = &BodyError{Error: fmt.Sprintf("must be between 0 and %d", maxPageSize), Field: "minResults"}
+
+// Relaxation is one constraint relaxSearch loosened, and how many results
+// the search found once it had.
+type Relaxation struct {
+	Constraint string `json:"constraint"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Results    int    `json:"results"`
+}
+
+// relaxer loosens one constraint in place, reporting false when there is
+// nothing left to loosen.
+type relaxer func(parameters *BiteBody, params *searchParams) (Relaxation, bool)
+
+// relaxers run in order: being open right now matters least to most users,
+// then price, and only then distance, widened a step at a time.
+var relaxers = []relaxer{relaxOpenNow, relaxPrice, relaxRadius, relaxRadius}
+
+func relaxOpenNow(parameters *BiteBody, params *searchParams) (Relaxation, bool) {
+	if params.IgnoreOpenNow {
+		return Relaxation{}, false
+	}
+	params.IgnoreOpenNow = true
+	return Relaxation{Constraint: filterOpenNow, From: "open now", To: "any time"}, true
+}
+
+func relaxPrice(parameters *BiteBody, params *searchParams) (Relaxation, bool) {
+	if params.MinPrice == 0 && params.MaxPrice == 0 && parameters.MaxPerPerson == 0 {
+		return Relaxation{}, false
+	}
+	from := fmt.Sprintf("%d-%d", params.MinPrice, params.MaxPrice)
+	if parameters.MaxPerPerson > 0 {
+		from = fmt.Sprintf("%s, max %.2f per person", from, parameters.MaxPerPerson)
+	}
+	params.MinPrice, params.MaxPrice = 0, 0
+	parameters.MinPrice, parameters.MaxPrice, parameters.MaxPerPerson = 0, 0, 0
+	return Relaxation{Constraint: filterPrice, From: from, To: "any"}, true
+}
+
+func relaxRadius(parameters *BiteBody, params *searchParams) (Relaxation, bool) {
+	radius := params.Radius
+	if radius == 0 {
+		radius = relaxBaseRadius
+	}
+	if radius >= maxRelaxedRadius {
+		return Relaxation{}, false
+	}
+	wider := radius * 2
+	if wider > maxRelaxedRadius {
+		wider = maxRelaxedRadius
+	}
+	params.Radius, parameters.Radius = wider, wider
+	return Relaxation{Constraint: filterRadius, From: fmt.Sprintf("%dm", radius), To: fmt.Sprintf("%dm", wider)}, true
+}
+
+// relaxSearch repeats a search that found fewer than parameters.MinResults,
+// loosening one constraint at a time, and returns what it loosened. The last
+// search wins even if it is still short, and params is left as it ran.
+func relaxSearch(ctx context.Context, biteArray *BiteResponse, parameters BiteBody, params *searchParams, tenant *tenantProfile, debug *SearchDebug) ([]Relaxation, error) {
+	var relaxed []Relaxation
+	for _, relax := range relaxers {
+		if len(biteArray.Results) >= parameters.MinResults {
+			break
+		}
+		relaxation, ok := relax(&parameters, params)
+		if !ok {
+			continue
+		}
+		next, err := filteredSearch(ctx, parameters, *params, tenant, debug)
+		if err != nil {
+			return nil, err
+		}
+		relaxation.Results = len(next.Results)
+		relaxed = append(relaxed, relaxation)
+		*biteArray = next
+	}
+	return relaxed, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+const (
+	reportOpen      = "open"
+	reportTriaged   = "triaged"
+	reportResolved  = "resolved"
+	reportDismissed = "dismissed"
+
+	// reportsStatusIndex is a GSI on the reports table with status as its
+	// hash key and createdAt as its range key.
+	reportsStatusIndex = "status-createdAt"
+	maxReportsListed   = 100
+	maxReportDetails   = 2000
+)
+
+var reportCategories = []string{"wrong-hours", "wrong-location", "closed", "wrong-info", "duplicate", "inappropriate-content", "other"}
+
+// reportTransitions lists, for each status, the statuses a report may move
+// to it from.
+var reportTransitions = map[string][]string{
+	reportTriaged:   {reportOpen},
+	reportResolved:  {reportOpen, reportTriaged},
+	reportDismissed: {reportOpen, reportTriaged},
+}
+
+type Report struct {
+	ReportID   string `dynamodbav:"reportId" json:"reportId"`
+	PlaceID    string `dynamodbav:"placeId" json:"placeId"`
+	Category   string `dynamodbav:"category" json:"category"`
+	Details    string `dynamodbav:"details" json:"details,omitempty"`
+	ClientID   string `dynamodbav:"clientId" json:"clientId,omitempty"`
+	Status     string `dynamodbav:"status" json:"status"`
+	Resolution string `dynamodbav:"resolution" json:"resolution,omitempty"`
+	CreatedAt  int64  `dynamodbav:"createdAt" json:"createdAt"`
+	UpdatedAt  int64  `dynamodbav:"updatedAt" json:"updatedAt,omitempty"`
+}
+
+func handleReport(ctx context.Context, clientID, placeID, category, details string) (events.APIGatewayProxyResponse, error) {
+	if cfg.ReportsTable == "" || placeID == "" || !contains(reportCategories, category) || len(details) > maxReportDetails {
+		return clientError(http.StatusBadRequest)
+	}
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		return serverError(err)
+	}
+	report := Report{
+		ReportID:  hex.EncodeToString(id),
+		PlaceID:   placeID,
+		Category:  category,
+		Details:   sanitizeText(details),
+		ClientID:  clientID,
+		Status:    reportOpen,
+		CreatedAt: time.Now().Unix(),
+	}
+	item, err := dynamodbattribute.MarshalMap(report)
+	if err != nil {
+		return serverError(err)
+	}
+	_, err = db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(cfg.ReportsTable),
+		Item:      item,
+	})
+	if err != nil {
+		return serverError(err)
+	}
+	emitMetric("ProblemReported", "Count", 1)
+	return jsonSuccess(report)
+}
+
+// handleListReports returns the oldest reports with a status, open by
+// default.
+func handleListReports(ctx context.Context, status string) (events.APIGatewayProxyResponse, error) {
+	if cfg.ReportsTable == "" {
+		return clientError(http.StatusNotFound)
+	}
+	if status == "" {
+		status = reportOpen
+	}
+	out, err := db.QueryWithContext(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(cfg.ReportsTable),
+		IndexName:              aws.String(reportsStatusIndex),
+		KeyConditionExpression: aws.String("#status = :status"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status": {S: aws.String(status)},
+		},
+		Limit: aws.Int64(maxReportsListed),
+	})
+	if err != nil {
+		return serverError(err)
+	}
+	reports := []Report{}
+	err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &reports)
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(map[string]interface{}{"reports": reports})
+}
+
+// handleUpdateReport moves a report along the triage workflow, recording an
+// optional resolution note. Moves reportTransitions doesn't allow, including
+// reopening a closed report, are a 409.
+func handleUpdateReport(ctx context.Context, reportID, status, resolution string) (events.APIGatewayProxyResponse, error) {
+	from, ok := reportTransitions[status]
+	if cfg.ReportsTable == "" || reportID == "" || !ok {
+		return clientError(http.StatusBadRequest)
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":status":     {S: aws.String(status)},
+		":resolution": {S: aws.String(sanitizeText(resolution))},
+		":now":        {N: aws.String(fmt.Sprint(time.Now().Unix()))},
+	}
+	placeholders := make([]string, len(from))
+	for i, s := range from {
+		placeholders[i] = fmt.Sprintf(":from%d", i)
+		values[placeholders[i]] = &dynamodb.AttributeValue{S: aws.String(s)}
+	}
+	out, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(cfg.ReportsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"reportId": {S: aws.String(reportID)},
+		},
+		UpdateExpression:    aws.String("SET #status = :status, resolution = :resolution, updatedAt = :now"),
+		ConditionExpression: aws.String(fmt.Sprintf("attribute_exists(reportId) AND #status IN (%s)", strings.Join(placeholders, ", "))),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: values,
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if isConditionalCheckFailed(err) {
+		return clientError(http.StatusConflict)
+	}
+	if err != nil {
+		return serverError(err)
+	}
+	var report Report
+	err = dynamodbattribute.UnmarshalMap(out.Attributes, &report)
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(report)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	reviewMinLetters    = 20
+	reviewMinCapsLength = 10
+	reviewCapsRatio     = 0.7
+	// reviewExcludeScore is how many heuristics a review must trip to be
+	// dropped; fewer only moves it to the end.
+	reviewExcludeScore = 2
+
+	reasonTooShort     = "too_short"
+	reasonAllCaps      = "all_caps"
+	reasonDuplicate    = "duplicate_text"
+	reasonExtremesOnly = "extreme_only_pattern"
+)
+
+// reviewFingerprints remembers which place each review text was first seen
+// at, to catch the same text posted across places.
+var reviewFingerprints = newTTLCache(7 * 24 * time.Hour)
+
+// ReviewFilter explains what happened to a suspicious review. It is only
+// returned in debug mode.
+type ReviewFilter struct {
+	AuthorName string   `json:"authorName"`
+	Time       int      `json:"time"`
+	Reasons    []string `json:"reasons"`
+	Action     string   `json:"action"`
+}
+
+// filterReviews down-ranks reviews that trip one spam heuristic and drops
+// those that trip reviewExcludeScore or more.
+func filterReviews(detail *PlaceDetail, debug bool) {
+	reviews := detail.Reviews
+	extremesOnly := len(reviews) > 1
+	for _, review := range reviews {
+		if review.Rating != 1 && review.Rating != 5 {
+			extremesOnly = false
+		}
+	}
+	var kept, downRanked []maps.PlaceReview
+	for _, review := range reviews {
+		reasons := reviewSpamReasons(detail.PlaceID, review)
+		if extremesOnly {
+			reasons = append(reasons, reasonExtremesOnly)
+		}
+		action := "kept"
+		switch {
+		case len(reasons) >= reviewExcludeScore:
+			action = "excluded"
+		case len(reasons) > 0:
+			action = "down_ranked"
+			downRanked = append(downRanked, review)
+		default:
+			kept = append(kept, review)
+		}
+		if debug && len(reasons) > 0 {
+			detail.ReviewFilters = append(detail.ReviewFilters, ReviewFilter{
+				AuthorName: review.AuthorName,
+				Time:       review.Time,
+				Reasons:    reasons,
+				Action:     action,
+			})
+		}
+	}
+	detail.Reviews = append(kept, downRanked...)
+}
+
+func reviewSpamReasons(placeID string, review maps.PlaceReview) []string {
+	var reasons []string
+	letters, upper := 0, 0
+	for _, r := range review.Text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters < reviewMinLetters {
+		reasons = append(reasons, reasonTooShort)
+	}
+	if letters >= reviewMinCapsLength && float64(upper)/float64(letters) > reviewCapsRatio {
+		reasons = append(reasons, reasonAllCaps)
+	}
+	if letters >= reviewMinLetters {
+		sum := sha256.Sum256([]byte(strings.Join(strings.Fields(strings.ToLower(review.Text)), " ")))
+		fingerprint := hex.EncodeToString(sum[:])
+		if seenAt, ok := reviewFingerprints.get(fingerprint); ok && seenAt.(string) != placeID {
+			reasons = append(reasons, reasonDuplicate)
+		} else if !ok {
+			reviewFingerprints.set(fingerprint, placeID)
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	profanityOff      = "off"
+	profanitySevere   = "severe"
+	profanityModerate = "moderate"
+	profanityMild     = "mild"
+
+	// maxRepeatedRune and maxSymbolRun cap "!!!!!!!!" and emoji floods.
+	// Digits are never capped.
+	maxRepeatedRune = 3
+	maxSymbolRun    = 5
+)
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`[ \t]+`)
+	wordPattern       = regexp.MustCompile(`[\p{L}']+`)
+)
+
+// profanityLevels ranks PROFANITY_FILTER settings; a word is masked when its
+// severity is at or above the configured level's threshold.
+var profanityLevels = map[string]int{
+	profanityOff:      4,
+	profanitySevere:   3,
+	profanityModerate: 2,
+	profanityMild:     1,
+}
+
+// profanity maps word stems to severity. Stems also match with common
+// suffixes ("-ing", "-ed", "-s").
+var profanity = map[string]int{
+	"damn":    1,
+	"crap":    1,
+	"hell":    1,
+	"piss":    1,
+	"shit":    2,
+	"ass":     2,
+	"asshole": 2,
+	"bitch":   2,
+	"bastard": 2,
+	"dick":    2,
+	"fuck":    3,
+	"cunt":    3,
+}
+
+var profanitySuffixes = []string{"", "s", "es", "ed", "er", "ers", "ing", "in", "y"}
+
+// sanitizeText cleans third-party and editorial text before it reaches
+// clients: HTML is stripped, runs of repeated characters and emoji are
+// capped, and profanity at or above PROFANITY_FILTER is masked.
+func sanitizeText(text string) string {
+	text = html.UnescapeString(htmlTagPattern.ReplaceAllString(text, " "))
+	text = capRuns(text)
+	text = maskProfanity(text, profanityLevels[cfg.ProfanityFilter])
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(whitespacePattern.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func capRuns(text string) string {
+	var b strings.Builder
+	var last rune
+	repeat, symbols := 0, 0
+	for _, r := range text {
+		if r == last {
+			repeat++
+		} else {
+			repeat = 1
+		}
+		last = r
+		if unicode.IsSymbol(r) || unicode.Is(unicode.Variation_Selector, r) || r == '\u200d' {
+			symbols++
+		} else if !unicode.IsSpace(r) {
+			symbols = 0
+		}
+		if (repeat > maxRepeatedRune && !unicode.IsDigit(r)) || symbols > maxSymbolRun {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func maskProfanity(text string, threshold int) string {
+	if threshold == 0 {
+		threshold = profanityLevels[profanityModerate]
+	}
+	return wordPattern.ReplaceAllStringFunc(text, func(word string) string {
+		if profanitySeverity(strings.ToLower(word)) < threshold {
+			return word
+		}
+		runes := []rune(word)
+		return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+	})
+}
+
+func profanitySeverity(word string) int {
+	for _, suffix := range profanitySuffixes {
+		stem := strings.TrimSuffix(word, suffix)
+		if stem == word && suffix != "" {
+			continue
+		}
+		if severity, ok := profanity[stem]; ok {
+			return severity
+		}
+	}
+	return 0
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+const shareTokenTTL = 7 * 24 * time.Hour
+
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} on Bite</title>
+<meta property="og:type" content="place">
+<meta property="og:site_name" content="Bite">
+<meta property="og:title" content="{{.Name}}">
+<meta property="og:description" content="{{.Description}}">
+{{if .ImageURL}}<meta property="og:image" content="{{.ImageURL}}">
+<meta name="twitter:card" content="summary_large_image">{{end}}
+<meta property="og:url" content="{{.PageURL}}">
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>{{.Description}}</p>
+<p><a href="{{.MapsURL}}">Open in Maps</a></p>
+</body>
+</html>
+`))
+
+type sharePageData struct {
+	Name        string
+	Description string
+	ImageURL    string
+	PageURL     string
+	MapsURL     string
+}
+
+type shareLink struct {
+	Token     string `json:"token"`
+	URL       string `json:"url"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+func handleShare(placeID string) (events.APIGatewayProxyResponse, error) {
+	if placeID == "" || cfg.ShareSecret == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	expires := time.Now().Add(shareTokenTTL).Unix()
+	token := signShareToken(placeID, expires)
+	return jsonSuccess(shareLink{
+		Token:     token,
+		URL:       shareURL(token),
+		ExpiresAt: expires,
+	})
+}
+
+func signShareToken(placeID string, expires int64) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s|%d", placeID, expires)))
+	return payload + "." + shareSignature(payload)
+}
+
+func shareSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte(cfg.ShareSecret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseShareToken returns the place ID a token was issued for, or false if
+// the token is malformed, forged or expired.
+func parseShareToken(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || cfg.ShareSecret == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(shareSignature(parts[0])), []byte(parts[1])) {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	fields := strings.Split(string(payload), "|")
+	if len(fields) != 2 {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", false
+	}
+	return fields[0], true
+}
+
+// shareURL builds links on SHARE_BASE_URL only; the request's Host header is
+// client-controlled and would let anyone mint links to another origin.
+func shareURL(token string) string {
+	return strings.TrimSuffix(cfg.ShareBaseURL, "/") + "/share/" + token
+}
+
+func handleSharePage(token string) (events.APIGatewayProxyResponse, error) {
+	placeID, ok := parseShareToken(token)
+	if !ok {
+		return clientError(http.StatusNotFound)
+	}
+	place, err := respondPlaceDetails(placeID,
+		maps.PlaceDetailsFieldMaskName,
+		maps.PlaceDetailsFieldMaskFormattedAddress,
+		maps.PlaceDetailsFieldMaskPhotos,
+		maps.PlaceDetailsFieldMaskRatings,
+		maps.PlaceDetailsFieldMaskUserRatingsTotal,
+		maps.PlaceDetailsFieldMaskURL,
+	)
+	if err != nil {
+		return detailsFailure(err)
+	}
+	data := sharePageData{
+		Name:        place.Name,
+		Description: place.FormattedAddress,
+		PageURL:     shareURL(token),
+		MapsURL:     place.URL,
+	}
+	if place.Rating > 0 {
+		data.Description = fmt.Sprintf("★ %.1f (%d reviews) · %s", place.Rating, place.UserRatingsTotal, place.FormattedAddress)
+	}
+	if len(place.Photos) > 0 {
+		data.ImageURL = data.PageURL + "/image"
+	}
+	if data.MapsURL == "" {
+		data.MapsURL = placeMapsURL(place.Name, placeID)
+	}
+	buf := new(bytes.Buffer)
+	err = sharePage.Execute(buf, data)
+	if err != nil {
+		return serverError(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode:      http.StatusOK,
+		Headers:         map[string]string{"Content-Type": "text/html; charset=utf-8", "Cache-Control": "public, max-age=3600"},
+		IsBase64Encoded: false,
+		Body:            buf.String(),
+	}, nil
+}
+
+// handleShareImage serves the place's first photo so unfurlers can fetch
+// og:image without the Google API key appearing in the page.
+func handleShareImage(token string) (events.APIGatewayProxyResponse, error) {
+	placeID, ok := parseShareToken(token)
+	if !ok {
+		return clientError(http.StatusNotFound)
+	}
+	place, err := respondPlaceDetails(placeID, maps.PlaceDetailsFieldMaskPhotos)
+	if err != nil {
+		return detailsFailure(err)
+	}
+	if len(place.Photos) == 0 {
+		return clientError(http.StatusNotFound)
+	}
+	resp, err := handlePhoto(place.Photos[0].PhotoReference)
+	if err == nil && resp.StatusCode == http.StatusOK {
+		resp.Headers = map[string]string{"Content-Type": "image/jpeg", "Cache-Control": "public, max-age=86400"}
+	}
+	return resp, err
+}
+
+func routeShare(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	token := req.PathParameters["token"]
+	path := strings.TrimPrefix(req.Path, "/share/")
+	if token == "" {
+		token = strings.TrimSuffix(path, "/image")
+	}
+	if strings.HasSuffix(path, "/image") {
+		return handleShareImage(token)
+	}
+	return handleSharePage(token)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const signatureMaxSkew = 5 * time.Minute
+
+type nonceCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+var nonces = &nonceCache{seen: map[string]time.Time{}}
+
+// claim records nonce and reports whether it had not been seen inside the
+// skew window. The cache lives for the lifetime of the container, so the
+// timestamp check is what bounds replays across containers.
+func (c *nonceCache) claim(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n, expires := range c.seen {
+		if now.After(expires) {
+			delete(c.seen, n)
+		}
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = now.Add(2 * signatureMaxSkew)
+	return true
+}
+
+func signRequest(secret, timestamp, nonce, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n" + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifySignature(req events.APIGatewayProxyRequest) int {
+	if cfg.DeviceSecret == "" {
+		return http.StatusOK
+	}
+	timestamp := headerValue(req.Headers, "X-Bite-Timestamp")
+	nonce := headerValue(req.Headers, "X-Bite-Nonce")
+	signature := headerValue(req.Headers, "X-Bite-Signature")
+	if timestamp == "" || nonce == "" || signature == "" {
+		return http.StatusUnauthorized
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return http.StatusUnauthorized
+	}
+	now := time.Now()
+	skew := now.Sub(time.Unix(seconds, 0))
+	if skew > signatureMaxSkew || skew < -signatureMaxSkew {
+		return http.StatusUnauthorized
+	}
+	expected := signRequest(cfg.DeviceSecret, timestamp, nonce, req.Body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return http.StatusUnauthorized
+	}
+	if !nonces.claim(nonce, now) {
+		return http.StatusConflict
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"googlemaps.github.io/maps"
+)
+
+const (
+	defaultSimilarCount  = 10
+	defaultSimilarRadius = 3000
+)
+
+var embeddingCache = newTTLCache(7 * 24 * time.Hour)
+
+// placeEmbedding is a stored vector with what it was computed from, so a
+// taxonomy release or a moved score makes it stale.
+type placeEmbedding struct {
+	PlaceID         string    `dynamodbav:"placeId"`
+	TaxonomyVersion int       `dynamodbav:"taxonomyVersion"`
+	Score           float64   `dynamodbav:"score"`
+	Vector          []float64 `dynamodbav:"vector"`
+}
+
+// current reports whether e was computed from the loaded taxonomy and score.
+func (e placeEmbedding) current(taxonomy *cuisineTaxonomy, score float64) bool {
+	return e.TaxonomyVersion == taxonomy.Version &&
+		len(e.Vector) == len(taxonomy.Cuisines)+2 &&
+		e.Score == score
+}
+
+// placeVector embeds a place as one-hots over the cuisine taxonomy plus
+// price and score, each in 0–1, normalized to unit length.
+func placeVector(result maps.PlacesSearchResult, score float64) []float64 {
+	taxonomy := loadedCuisines().Cuisines
+	vector := make([]float64, len(taxonomy)+2)
+	tagged := classifyCuisines(BiteResult{PlacesSearchResult: result})
+	for i, def := range taxonomy {
+		if hasAny(tagged, []string{def.ID}) {
+			vector[i] = 1
+		}
+	}
+	vector[len(taxonomy)] = float64(result.PriceLevel) / 4
+	vector[len(taxonomy)+1] = score / 100
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] /= norm
+		}
+	}
+	return vector
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
+
+// storedEmbedding reads a place's vector from the per-container cache, then
+// EMBEDDINGS_TABLE when configured, computing and storing it on a miss or
+// when the stored vector predates the loaded taxonomy or the place's score.
+func storedEmbedding(ctx context.Context, result maps.PlacesSearchResult, score float64) []float64 {
+	taxonomy := loadedCuisines()
+	if cached, ok := embeddingCache.get(result.PlaceID); ok {
+		if embedding := cached.(placeEmbedding); embedding.current(taxonomy, score) {
+			return embedding.Vector
+		}
+	}
+	if cfg.EmbeddingsTable != "" {
+		out, err := db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(cfg.EmbeddingsTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"placeId": {S: aws.String(result.PlaceID)},
+			},
+		})
+		var stored placeEmbedding
+		if err == nil && len(out.Item) > 0 && dynamodbattribute.UnmarshalMap(out.Item, &stored) == nil && stored.current(taxonomy, score) {
+			embeddingCache.set(result.PlaceID, stored)
+			return stored.Vector
+		}
+	}
+	embedding := placeEmbedding{
+		PlaceID:         result.PlaceID,
+		TaxonomyVersion: taxonomy.Version,
+		Score:           score,
+		Vector:          placeVector(result, score),
+	}
+	embeddingCache.set(result.PlaceID, embedding)
+	if cfg.EmbeddingsTable != "" {
+		item, err := dynamodbattribute.MarshalMap(embedding)
+		if err == nil {
+			_, err = db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+				TableName: aws.String(cfg.EmbeddingsTable),
+				Item:      item,
+			})
+		}
+		if err != nil {
+			errorLogger.Println(err)
+		}
+	}
+	return embedding.Vector
+}
+
+// handleSimilar returns the places within the user's radius nearest to
+// placeID by embedding, for "more like this".
+func handleSimilar(ctx context.Context, parameters BiteBody, tenant *tenantProfile) (events.APIGatewayProxyResponse, error) {
+	if parameters.PlaceID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	place, err := respondPlaceDetails(parameters.PlaceID,
+		maps.PlaceDetailsFieldMaskPlaceID,
+		maps.PlaceDetailsFieldMaskName,
+		maps.PlaceDetailsFieldMaskGeometry,
+		maps.PlaceDetailsFieldMaskTypes,
+		maps.PlaceDetailsFieldMaskPriceLevel,
+		maps.PlaceDetailsFieldMaskRatings,
+		maps.PlaceDetailsFieldMaskUserRatingsTotal,
+	)
+	if err != nil {
+		return detailsFailure(err)
+	}
+	target := BiteResult{PlacesSearchResult: maps.PlacesSearchResult{
+		PlaceID:          parameters.PlaceID,
+		Name:             place.Name,
+		Types:            place.Types,
+		PriceLevel:       place.PriceLevel,
+		Rating:           place.Rating,
+		UserRatingsTotal: place.UserRatingsTotal,
+		Geometry:         place.Geometry,
+	}, SourceRatings: map[string]SourceRating{providerGoogle: newSourceRating(providerGoogle, place.Rating, place.UserRatingsTotal)}}
+	normalizeRatings(&target)
+	targetVector := storedEmbedding(ctx, target.PlacesSearchResult, target.Score)
+
+	params := searchParams{Lat: parameters.Lat, Long: parameters.Long, Radius: parameters.Radius}
+	if params.Lat == 0 && params.Long == 0 {
+		params.Lat, params.Long = place.Geometry.Location.Lat, place.Geometry.Location.Lng
+	}
+	if params.Radius == 0 {
+		params.Radius = defaultSimilarRadius
+	}
+	biteArray, err := searchPlaces(ctx, params, providersFor(tenant))
+	if err != nil {
+		return serverError(err)
+	}
+	candidates := biteArray.Results[:0]
+	for _, result := range biteArray.Results {
+		if result.PlaceID == parameters.PlaceID {
+			continue
+		}
+		result.Similarity = math.Round(cosine(targetVector, storedEmbedding(ctx, result.PlacesSearchResult, result.Score))*1000) / 1000
+		candidates = append(candidates, result)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Similarity > candidates[j].Similarity })
+	count := parameters.Count
+	if count <= 0 {
+		count = defaultSimilarCount
+	}
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+	biteArray.Results = candidates
+	biteArray.NextPageToken = ""
+	return clientSuccess(biteArray), nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	placeDetailsURL  = "https://maps.googleapis.com/maps/api/place/details/json"
+	specialDayFormat = "2006-01-02"
+)
+
+var currentHoursCache = newTTLCache(6 * time.Hour)
+
+type SpecialDay struct {
+	Date             string `json:"date"`
+	ExceptionalHours bool   `json:"exceptionalHours"`
+}
+
+// currentOpeningHours is the part of Place Details' current_opening_hours the
+// maps client doesn't decode: this week's actual periods, holidays included,
+// and the dates that differ from the regular schedule.
+type currentOpeningHours struct {
+	OpenNow     *bool                     `json:"open_now"`
+	Periods     []maps.OpeningHoursPeriod `json:"periods"`
+	SpecialDays []struct {
+		Date             string `json:"date"`
+		ExceptionalHours bool   `json:"exceptional_hours"`
+	} `json:"special_days"`
+}
+
+// fetchCurrentOpeningHours calls the Details endpoint directly, which only
+// takes the API key in the query string, so transport errors are returned
+// without the request URL to keep the key out of the logs.
+func fetchCurrentOpeningHours(ctx context.Context, placeID string) (*currentOpeningHours, error) {
+	if strings.HasPrefix(placeID, osmIDPrefix) {
+		return nil, &detailsUnsupportedError{placeID: placeID}
+	}
+	if cached, ok := currentHoursCache.get(placeID); ok {
+		return cached.(*currentOpeningHours), nil
+	}
+	query := url.Values{
+		"place_id": {placeID},
+		"fields":   {"current_opening_hours"},
+		"key":      {cfg.GoogleAPIKey},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, placeDetailsURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return nil, fmt.Errorf("place details: %s: %w", urlErr.Op, urlErr.Err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Status string `json:"status"`
+		Result struct {
+			CurrentOpeningHours *currentOpeningHours `json:"current_opening_hours"`
+		} `json:"result"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	if body.Status != "OK" {
+		return nil, fmt.Errorf("place details: %s", body.Status)
+	}
+	currentHoursCache.set(placeID, body.Result.CurrentOpeningHours)
+	return body.Result.CurrentOpeningHours, nil
+}
+
+// applySpecialDays adds holiday exceptions to a schedule. When today is
+// exceptional, the open-now fields are recomputed from this week's actual
+// periods and a warning is set, since the regular hours are wrong today.
+func applySpecialDays(ctx context.Context, schedule *WeeklySchedule, placeID string, loc *time.Location, now time.Time) {
+	if schedule == nil || strings.HasPrefix(placeID, foursquareIDPrefix) || strings.HasPrefix(placeID, fixtureIDPrefix) {
+		return
+	}
+	current, err := fetchCurrentOpeningHours(ctx, placeID)
+	if err != nil {
+		logDetailsError(err)
+		return
+	}
+	if current == nil {
+		return
+	}
+	today := now.In(loc).Format(specialDayFormat)
+	for _, day := range current.SpecialDays {
+		schedule.SpecialDays = append(schedule.SpecialDays, SpecialDay{Date: day.Date, ExceptionalHours: day.ExceptionalHours})
+		if day.Date == today {
+			schedule.ExceptionalToday = true
+		}
+	}
+	if !schedule.ExceptionalToday {
+		return
+	}
+	schedule.Warning = "Hours today differ from the regular schedule"
+	actual := normalizeHours(&maps.OpeningHours{Periods: current.Periods}, loc, now)
+	if actual == nil {
+		schedule.IsOpenNow = current.OpenNow != nil && *current.OpenNow
+		schedule.ClosesInMinutes = nil
+		schedule.NextOpenAt = nil
+		return
+	}
+	schedule.IsOpenNow = actual.IsOpenNow
+	schedule.ClosesInMinutes = actual.ClosesInMinutes
+	schedule.NextOpenAt = actual.NextOpenAt
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+const maxSuggestions = 3
+
+// commonFoodTerms supplements the cuisine taxonomy with dishes people search
+// for by name.
+var commonFoodTerms = []string{
+	"pizza", "burger", "sushi", "ramen", "pho", "tacos", "burrito", "noodles", "dumplings", "curry",
+	"sandwich", "salad", "steak", "chicken", "wings", "bagel", "donut", "dessert", "ice cream",
+	"brunch", "breakfast", "coffee", "tea", "boba", "bbq", "seafood", "oysters", "poke", "falafel",
+	"shawarma", "kebab", "gyro", "paella", "tapas", "dim sum", "pasta", "lasagna", "crepes", "waffles",
+}
+
+// spellingVocabulary is every term a keyword can be corrected to.
+func spellingVocabulary() []string {
+	seen := map[string]bool{}
+	var terms []string
+	add := func(term string) {
+		term = strings.ToLower(term)
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, def := range loadedCuisines().Cuisines {
+		add(strings.ReplaceAll(def.ID, "_", " "))
+		for _, keyword := range def.Keywords {
+			add(keyword)
+		}
+	}
+	for _, term := range commonFoodTerms {
+		add(term)
+	}
+	return terms
+}
+
+// suggestCorrections proposes respellings of a keyword that found nothing:
+// the whole keyword with each word corrected, followed by the closest single
+// terms.
+func suggestCorrections(keyword string) []string {
+	vocabulary := spellingVocabulary()
+	words := strings.Fields(strings.ToLower(keyword))
+	corrected := make([]string, len(words))
+	changed := false
+	type candidate struct {
+		term     string
+		distance int
+	}
+	var candidates []candidate
+	for i, word := range words {
+		corrected[i] = word
+		best, bestDistance := "", maxEditsFor(word)+1
+		for _, term := range vocabulary {
+			if term == word {
+				best, bestDistance = "", 0
+				break
+			}
+			d := editDistance(word, term)
+			if d <= maxEditsFor(word) {
+				candidates = append(candidates, candidate{term, d})
+			}
+			if d < bestDistance {
+				best, bestDistance = term, d
+			}
+		}
+		if best != "" {
+			corrected[i] = best
+			changed = true
+		}
+	}
+	var suggestions []string
+	if changed {
+		suggestions = append(suggestions, strings.Join(corrected, " "))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	for _, c := range candidates {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		if !contains(suggestions, c.term) {
+			suggestions = append(suggestions, c.term)
+		}
+	}
+	return suggestions
+}
+
+// maxEditsFor allows one typo in short words and two in longer ones.
+func maxEditsFor(word string) int {
+	if len([]rune(word)) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance is the Damerau–Levenshtein distance (optimal string
+// alignment), so swapped letters count as one edit.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(minInt(d[i-1][j]+1, d[i][j-1]+1), d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"googlemaps.github.io/maps"
+)
+
+const (
+	sponsoredLabel    = "Sponsored"
+	sponsoredPosition = 2
+	campaignsCacheKey = "campaigns"
+)
+
+var campaignsCache = newTTLCache(time.Minute)
+
+type Campaign struct {
+	CampaignID   string  `dynamodbav:"campaignId" json:"campaignId"`
+	PlaceID      string  `dynamodbav:"placeId" json:"placeId"`
+	Lat          float64 `dynamodbav:"lat" json:"lat"`
+	Long         float64 `dynamodbav:"long" json:"long"`
+	RadiusMeters uint    `dynamodbav:"radiusMeters" json:"radiusMeters"`
+	StartsAt     int64   `dynamodbav:"startsAt" json:"startsAt"`
+	EndsAt       int64   `dynamodbav:"endsAt" json:"endsAt"`
+	Budget       int     `dynamodbav:"budget" json:"budget"`
+	Impressions  int     `dynamodbav:"impressions" json:"impressions"`
+	Clicks       int     `dynamodbav:"clicks" json:"clicks"`
+}
+
+type SponsoredLabel struct {
+	CampaignID string `json:"campaignId"`
+	Label      string `json:"label"`
+}
+
+func (c Campaign) eligible(location maps.LatLng, now time.Time) bool {
+	if now.Unix() < c.StartsAt || now.Unix() >= c.EndsAt || c.Impressions >= c.Budget {
+		return false
+	}
+	return distanceMeters(location, maps.LatLng{Lat: c.Lat, Lng: c.Long}) <= float64(c.RadiusMeters)
+}
+
+func handleCreateCampaign(ctx context.Context, placeID string, lat, long float64, radius uint, startTime, endTime string, budget int) (events.APIGatewayProxyResponse, error) {
+	if cfg.CampaignsTable == "" || placeID == "" || radius == 0 || budget <= 0 {
+		return clientError(http.StatusBadRequest)
+	}
+	start, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		return clientError(http.StatusBadRequest)
+	}
+	end, err := time.Parse(time.RFC3339, endTime)
+	if err != nil || !end.After(start) {
+		return clientError(http.StatusBadRequest)
+	}
+	id := make([]byte, 8)
+	_, err = rand.Read(id)
+	if err != nil {
+		return serverError(err)
+	}
+	campaign := Campaign{
+		CampaignID:   hex.EncodeToString(id),
+		PlaceID:      placeID,
+		Lat:          lat,
+		Long:         long,
+		RadiusMeters: radius,
+		StartsAt:     start.Unix(),
+		EndsAt:       end.Unix(),
+		Budget:       budget,
+	}
+	item, err := dynamodbattribute.MarshalMap(campaign)
+	if err != nil {
+		return serverError(err)
+	}
+	_, err = db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(cfg.CampaignsTable),
+		Item:      item,
+	})
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(campaign)
+}
+
+func handleEndCampaign(ctx context.Context, campaignID string) (events.APIGatewayProxyResponse, error) {
+	if cfg.CampaignsTable == "" || campaignID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	out, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(cfg.CampaignsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"campaignId": {S: aws.String(campaignID)},
+		},
+		UpdateExpression:    aws.String("SET endsAt = :now"),
+		ConditionExpression: aws.String("attribute_exists(campaignId)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(fmt.Sprint(time.Now().Unix()))},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if isConditionalCheckFailed(err) {
+		return clientError(http.StatusNotFound)
+	}
+	if err != nil {
+		return serverError(err)
+	}
+	var campaign Campaign
+	err = dynamodbattribute.UnmarshalMap(out.Attributes, &campaign)
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(campaign)
+}
+
+func handleSponsoredClick(ctx context.Context, campaignID string) (events.APIGatewayProxyResponse, error) {
+	if cfg.CampaignsTable == "" || campaignID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	_, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(cfg.CampaignsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"campaignId": {S: aws.String(campaignID)},
+		},
+		UpdateExpression:    aws.String("ADD clicks :one"),
+		ConditionExpression: aws.String("attribute_exists(campaignId)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":one": {N: aws.String("1")},
+		},
+	})
+	if isConditionalCheckFailed(err) {
+		return clientError(http.StatusNotFound)
+	}
+	if err != nil {
+		return serverError(err)
+	}
+	logSponsoredEvent("click", campaignID)
+	return jsonSuccess(map[string]string{"campaignId": campaignID, "status": "recorded"})
+}
+
+func activeCampaigns(ctx context.Context) ([]Campaign, error) {
+	if cached, ok := campaignsCache.get(campaignsCacheKey); ok {
+		return cached.([]Campaign), nil
+	}
+	var campaigns []Campaign
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(cfg.CampaignsTable),
+		FilterExpression: aws.String("endsAt > :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(fmt.Sprint(time.Now().Unix()))},
+		},
+	}
+	for {
+		out, err := db.ScanWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		var page []Campaign
+		err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &page)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, page...)
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+	campaignsCache.set(campaignsCacheKey, campaigns)
+	return campaigns, nil
+}
+
+// recordImpression charges one impression against the campaign's budget,
+// failing once the budget is spent so other containers can't overspend it.
+func recordImpression(ctx context.Context, campaign Campaign) (bool, error) {
+	_, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(cfg.CampaignsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"campaignId": {S: aws.String(campaign.CampaignID)},
+		},
+		UpdateExpression:    aws.String("ADD impressions :one"),
+		ConditionExpression: aws.String("attribute_not_exists(impressions) OR impressions < budget"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":one": {N: aws.String("1")},
+		},
+	})
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// sponsoredCapabilities emulates every provider-side filter, since a
+// campaign's place comes from Place Details rather than the search.
+var sponsoredCapabilities = providerCapabilities{
+	filterKeyword:  filterEmulated,
+	filterMealType: filterEmulated,
+	filterOpenNow:  filterEmulated,
+	filterPrice:    filterEmulated,
+	filterRadius:   filterEmulated,
+}
+
+// injectSponsored adds at most one labelled sponsored result for a campaign
+// whose geofence covers the search. The place must pass the same filters as
+// the organic results, and is never the only result. Places already in the
+// organic results aren't injected again.
+func injectSponsored(ctx context.Context, parameters BiteBody, params searchParams, biteArray *BiteResponse) {
+	if cfg.CampaignsTable == "" || len(biteArray.Results) == 0 {
+		return
+	}
+	campaigns, err := activeCampaigns(ctx)
+	if err != nil {
+		errorLogger.Println(err)
+		return
+	}
+	location := maps.LatLng{Lat: params.Lat, Lng: params.Long}
+	now := time.Now()
+	for _, campaign := range campaigns {
+		if !campaign.eligible(location, now) || containsPlace(biteArray.Results, campaign.PlaceID) {
+			continue
+		}
+		place, err := respondPlaceDetails(campaign.PlaceID,
+			maps.PlaceDetailsFieldMaskPlaceID,
+			maps.PlaceDetailsFieldMaskName,
+			maps.PlaceDetailsFieldMaskGeometry,
+			maps.PlaceDetailsFieldMaskVicinity,
+			maps.PlaceDetailsFieldMaskPhotos,
+			maps.PlaceDetailsFieldMaskRatings,
+			maps.PlaceDetailsFieldMaskUserRatingsTotal,
+			maps.PlaceDetailsFieldMaskPriceLevel,
+			maps.PlaceDetailsFieldMaskTypes,
+			maps.PlaceDetailsFieldMaskBusinessStatus,
+			maps.PlaceDetailsFieldMaskOpeningHours,
+		)
+		if err != nil {
+			errorLogger.Println(err)
+			continue
+		}
+		sponsored, ok := sponsoredResult(ctx, place, parameters, params)
+		if !ok {
+			continue
+		}
+		charged, err := recordImpression(ctx, campaign)
+		if err != nil {
+			errorLogger.Println(err)
+		}
+		if !charged {
+			continue
+		}
+		logSponsoredEvent("impression", campaign.CampaignID)
+		sponsored.Sponsored = &SponsoredLabel{CampaignID: campaign.CampaignID, Label: sponsoredLabel}
+		position := sponsoredPosition
+		if position > len(biteArray.Results) {
+			position = len(biteArray.Results)
+		}
+		biteArray.Results = append(biteArray.Results[:position], append([]BiteResult{sponsored}, biteArray.Results[position:]...)...)
+		return
+	}
+}
+
+// sponsoredResult runs a campaign's place through the search's filters,
+// reporting whether it survived them.
+func sponsoredResult(ctx context.Context, place maps.PlaceDetailsResult, parameters BiteBody, params searchParams) (BiteResult, bool) {
+	resp := maps.PlacesSearchResponse{Results: []maps.PlacesSearchResult{{
+		PlaceID:          place.PlaceID,
+		Name:             place.Name,
+		Geometry:         place.Geometry,
+		Vicinity:         place.Vicinity,
+		Photos:           place.Photos,
+		Rating:           place.Rating,
+		UserRatingsTotal: place.UserRatingsTotal,
+		PriceLevel:       place.PriceLevel,
+		Types:            place.Types,
+		BusinessStatus:   place.BusinessStatus,
+		OpeningHours:     place.OpeningHours,
+	}}}
+	applyCapabilities(sponsoredCapabilities, params, &resp)
+	candidate := newBiteResponse(resp, providerGoogle)
+	parameters.SortBy = ""
+	applyFilters(ctx, &candidate, parameters, params, nil)
+	if len(candidate.Results) == 0 {
+		return BiteResult{}, false
+	}
+	return candidate.Results[0], true
+}
+
+func containsPlace(results []BiteResult, placeID string) bool {
+	for _, result := range results {
+		if result.PlaceID == placeID {
+			return true
+		}
+	}
+	return false
+}
+
+func logSponsoredEvent(event, campaignID string) {
+	log.Printf(`{"event":"sponsored_%s","campaignId":%q,"at":%d}`, event, campaignID, time.Now().Unix())
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+var errCircuitOpen = errors.New("circuit open")
+
+// circuitBreaker stops calling a provider after cfg.BreakerFailures
+// consecutive failures. Once cfg.BreakerCooldown has passed it lets a single
+// trial call through, and closes again when one succeeds.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+var breakers = struct {
+	sync.Mutex
+	byProvider map[string]*circuitBreaker
+}{byProvider: map[string]*circuitBreaker{}}
+
+func breakerFor(provider string) *circuitBreaker {
+	breakers.Lock()
+	defer breakers.Unlock()
+	b, ok := breakers.byProvider[provider]
+	if !ok {
+		b = &circuitBreaker{}
+		breakers.byProvider[provider] = b
+	}
+	return b
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < cfg.BreakerFailures {
+		return true
+	}
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	b.openUntil = now.Add(cfg.BreakerCooldown)
+	return true
+}
+
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures == cfg.BreakerFailures {
+		b.openUntil = time.Now().Add(cfg.BreakerCooldown)
+		emitMetric("CircuitOpened", "Count", 1)
+	}
+}
+
+// staleEntry is a provider's last good answer for an area, kept for
+// cfg.StaleMaxAge to fall back on while the provider is down.
+type staleEntry struct {
+	Resp    maps.PlacesSearchResponse `json:"resp"`
+	Filters map[string]string         `json:"filters"`
+	At      time.Time                 `json:"at"`
+}
+
+var staleResults struct {
+	once  sync.Once
+	cache *layeredCache
+}
+
+func staleCache() *layeredCache {
+	staleResults.once.Do(func() {
+		staleResults.cache = newLayeredCache("stale", cfg.StaleMaxAge)
+	})
+	return staleResults.cache
+}
+
+var freshResults struct {
+	once  sync.Once
+	cache *layeredCache
+}
+
+// searchCache holds provider results for cfg.SearchCacheTTL, so repeated
+// searches of an area are answered without a call.
+func searchCache() *layeredCache {
+	freshResults.once.Do(func() {
+		freshResults.cache = newLayeredCache("search", cfg.SearchCacheTTL)
+	})
+	return freshResults.cache
+}
+
+// cachedResults returns the area's recent results from searchCache.
+func cachedResults(ctx context.Context, p placesProvider, params searchParams) (providerResult, bool) {
+	if cfg.SearchCacheTTL <= 0 {
+		return providerResult{}, false
+	}
+	var entry staleEntry
+	if !searchCache().get(ctx, searchKey("search", p.Name(), params), &entry) {
+		return providerResult{}, false
+	}
+	return providerResult{name: p.Name(), resp: entry.Resp, filters: entry.Filters}, true
+}
+
+// searchKey groups kind's entries by provider, filters and a roughly 100m
+// cell, so everyone searching the same area shares them.
+func searchKey(kind, provider string, params searchParams) string {
+	return cacheKey(kind, provider, fmt.Sprintf("%.3f,%.3f", params.Lat, params.Long), params.Radius,
+		fmt.Sprintf("%d-%d", params.MinPrice, params.MaxPrice), params.Keyword, params.MealType, params.IgnoreOpenNow)
+}
+
+// rememberResults keeps a good provider result both as the area's recent
+// results and as its fallback.
+func rememberResults(ctx context.Context, r providerResult, params searchParams) {
+	entry := staleEntry{Resp: r.resp, Filters: r.filters, At: time.Now()}
+	if cfg.SearchCacheTTL > 0 {
+		searchCache().set(ctx, searchKey("search", r.name, params), entry)
+	}
+	if cfg.StaleMaxAge > 0 {
+		staleCache().set(ctx, searchKey("stale", r.name, params), entry)
+	}
+}
+
+// staleResult replaces a failed provider result with the last good one for
+// the area, if there is one young enough.
+func staleResult(ctx context.Context, r providerResult, params searchParams) providerResult {
+	if cfg.StaleMaxAge <= 0 {
+		return r
+	}
+	var entry staleEntry
+	if !staleCache().get(ctx, searchKey("stale", r.name, params), &entry) {
+		return r
+	}
+	emitMetric("StaleResultsServed", "Count", 1)
+	errorLogger.Printf("provider %s: %s; serving results from %s", r.name, r.err, entry.At.Format(time.RFC3339))
+	return providerResult{name: r.name, resp: entry.Resp, filters: entry.Filters, timing: r.timing, dataAsOf: &entry.At}
+}
+
+// markStale flags the response as stale, keeping the oldest dataAsOf when
+// several providers were served from the fallback.
+func (m *ResponseMeta) markStale(dataAsOf *time.Time) {
+	if dataAsOf == nil {
+		return
+	}
+	m.Stale = true
+	if m.DataAsOf == nil || dataAsOf.Before(*m.DataAsOf) {
+		m.DataAsOf = dataAsOf
+	}
+}
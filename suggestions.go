@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+const (
+	suggestionTag        = "tag"
+	suggestionCorrection = "correction"
+
+	suggestionPending  = "pending"
+	suggestionApproved = "approved"
+	suggestionRejected = "rejected"
+
+	// suggestionsStatusIndex is a GSI on the suggestions table with status as
+	// its hash key and createdAt as its range key.
+	suggestionsStatusIndex = "status-createdAt"
+	maxSuggestionsListed   = 100
+
+	correctionPermanentlyClosed = "permanently-closed"
+	correctionTemporarilyClosed = "temporarily-closed"
+
+	communityConcurrency = 5
+)
+
+// communityTags are the tags users may suggest, on top of the ambiance tags.
+var communityTags = []string{"cash-only", "card-only", "good-for-kids", "allows-dogs", "outdoor-seating", "wifi", communityDriveThrough}
+
+var corrections = []string{correctionPermanentlyClosed, correctionTemporarilyClosed}
+
+type Suggestion struct {
+	SuggestionID string `dynamodbav:"suggestionId" json:"suggestionId"`
+	PlaceID      string `dynamodbav:"placeId" json:"placeId"`
+	Kind         string `dynamodbav:"kind" json:"kind"`
+	Value        string `dynamodbav:"value" json:"value"`
+	Note         string `dynamodbav:"note" json:"note,omitempty"`
+	ClientID     string `dynamodbav:"clientId" json:"clientId,omitempty"`
+	Status       string `dynamodbav:"status" json:"status"`
+	CreatedAt    int64  `dynamodbav:"createdAt" json:"createdAt"`
+	ReviewedAt   int64  `dynamodbav:"reviewedAt" json:"reviewedAt,omitempty"`
+}
+
+func validSuggestion(kind, value string) bool {
+	switch kind {
+	case suggestionTag:
+		return knownAmbiance(value) || contains(communityTags, value)
+	case suggestionCorrection:
+		return contains(corrections, value)
+	}
+	return false
+}
+
+// handleSuggest queues a user's tag or correction for a place. Nothing is
+// shown to other users until an admin approves it.
+func handleSuggest(ctx context.Context, clientID, placeID, kind, value, note string) (events.APIGatewayProxyResponse, error) {
+	if cfg.SuggestionsTable == "" || placeID == "" || !validSuggestion(kind, value) {
+		return clientError(http.StatusBadRequest)
+	}
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		return serverError(err)
+	}
+	suggestion := Suggestion{
+		SuggestionID: hex.EncodeToString(id),
+		PlaceID:      placeID,
+		Kind:         kind,
+		Value:        value,
+		Note:         sanitizeText(note),
+		ClientID:     clientID,
+		Status:       suggestionPending,
+		CreatedAt:    time.Now().Unix(),
+	}
+	item, err := dynamodbattribute.MarshalMap(suggestion)
+	if err != nil {
+		return serverError(err)
+	}
+	_, err = db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(cfg.SuggestionsTable),
+		Item:      item,
+	})
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(suggestion)
+}
+
+// handleListSuggestions returns the oldest suggestions with a status,
+// pending by default, for the moderation queue.
+func handleListSuggestions(ctx context.Context, status string) (events.APIGatewayProxyResponse, error) {
+	if cfg.SuggestionsTable == "" {
+		return clientError(http.StatusNotFound)
+	}
+	if status == "" {
+		status = suggestionPending
+	}
+	out, err := db.QueryWithContext(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(cfg.SuggestionsTable),
+		IndexName:              aws.String(suggestionsStatusIndex),
+		KeyConditionExpression: aws.String("#status = :status"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status": {S: aws.String(status)},
+		},
+		Limit: aws.Int64(maxSuggestionsListed),
+	})
+	if err != nil {
+		return serverError(err)
+	}
+	suggestions := []Suggestion{}
+	err = dynamodbattribute.UnmarshalListOfMaps(out.Items, &suggestions)
+	if err != nil {
+		return serverError(err)
+	}
+	return jsonSuccess(map[string]interface{}{"suggestions": suggestions})
+}
+
+// handleReviewSuggestion approves or rejects a pending suggestion. Approved
+// values are added to the place's community tags, which search results pick
+// up once the place's cached tags expire.
+func handleReviewSuggestion(ctx context.Context, suggestionID, status string) (events.APIGatewayProxyResponse, error) {
+	if cfg.SuggestionsTable == "" || suggestionID == "" {
+		return clientError(http.StatusBadRequest)
+	}
+	out, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(cfg.SuggestionsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"suggestionId": {S: aws.String(suggestionID)},
+		},
+		UpdateExpression:    aws.String("SET #status = :status, reviewedAt = :now"),
+		ConditionExpression: aws.String("#status = :pending"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status":  {S: aws.String(status)},
+			":pending": {S: aws.String(suggestionPending)},
+			":now":     {N: aws.String(fmt.Sprint(time.Now().Unix()))},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if isConditionalCheckFailed(err) {
+		return clientError(http.StatusNotFound)
+	}
+	if err != nil {
+		return serverError(err)
+	}
+	var suggestion Suggestion
+	err = dynamodbattribute.UnmarshalMap(out.Attributes, &suggestion)
+	if err != nil {
+		return serverError(err)
+	}
+	if status == suggestionApproved {
+		err = addPlaceTag(ctx, suggestion.PlaceID, suggestion.Value)
+		if err != nil {
+			return serverError(err)
+		}
+	}
+	return jsonSuccess(suggestion)
+}
+
+func addPlaceTag(ctx context.Context, placeID, tag string) error {
+	if cfg.PlaceTagsTable == "" {
+		return nil
+	}
+	_, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(cfg.PlaceTagsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"placeId": {S: aws.String(placeID)},
+		},
+		UpdateExpression: aws.String("ADD tags :tag"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":tag": {SS: []*string{aws.String(tag)}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	placeTagsCache.delete(placeID)
+	ambianceCache.delete(placeID)
+	return nil
+}
+
+// enrichCommunity attaches approved community tags to results and applies
+// approved closure corrections to their business status, so the closure
+// filters treat them like provider data.
+func enrichCommunity(ctx context.Context, biteArray *BiteResponse) {
+	if cfg.PlaceTagsTable == "" {
+		return
+	}
+	sem := make(chan struct{}, communityConcurrency)
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		wg.Add(1)
+		go func(result *BiteResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			for _, tag := range placeTags(ctx, result.PlaceID) {
+				switch {
+				case tag == correctionPermanentlyClosed:
+					result.BusinessStatus = businessClosedPermanently
+				case tag == correctionTemporarilyClosed && result.BusinessStatus != businessClosedPermanently:
+					result.BusinessStatus = businessClosedTemporarily
+				case contains(communityTags, tag):
+					result.CommunityTags = append(result.CommunityTags, tag)
+				}
+			}
+		}(&biteArray.Results[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+var placeTagsCache = newTTLCache(10 * time.Minute)
+
+// placeTagRecord is the community data kept for a place in
+// PLACE_TAGS_TABLE, keyed by placeId.
+type placeTagRecord struct {
+	PlaceID string   `dynamodbav:"placeId"`
+	Tags    []string `dynamodbav:"tags,stringset,omitempty"`
+}
+
+// placeTags returns the community tags stored for a place, or nil when the
+// table isn't configured or can't be read.
+func placeTags(ctx context.Context, placeID string) []string {
+	if cfg.PlaceTagsTable == "" {
+		return nil
+	}
+	if cached, ok := placeTagsCache.get(placeID); ok {
+		return cached.([]string)
+	}
+	out, err := db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(cfg.PlaceTagsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"placeId": {S: aws.String(placeID)},
+		},
+	})
+	if err != nil {
+		errorLogger.Println(err)
+		return nil
+	}
+	var record placeTagRecord
+	if len(out.Item) > 0 {
+		err = dynamodbattribute.UnmarshalMap(out.Item, &record)
+		if err != nil {
+			errorLogger.Println(err)
+			return nil
+		}
+	}
+	placeTagsCache.set(placeID, record.Tags)
+	return record.Tags
+}
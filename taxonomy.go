@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+const taxonomyMaxAge = 24 * 60 * 60
+
+type TaxonomyEntry struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	// Param is the request field that selects this entry, where there is one.
+	Param string `json:"param,omitempty"`
+}
+
+type Taxonomy struct {
+	Version        string          `json:"version"`
+	Cuisines       []TaxonomyEntry `json:"cuisines"`
+	DietaryTags    []TaxonomyEntry `json:"dietaryTags"`
+	AmbianceTags   []TaxonomyEntry `json:"ambianceTags"`
+	ServiceOptions []TaxonomyEntry `json:"serviceOptions"`
+	SortKeys       []TaxonomyEntry `json:"sortKeys"`
+	MealTypes      []TaxonomyEntry `json:"mealTypes"`
+	PriceLevels    []TaxonomyEntry `json:"priceLevels"`
+}
+
+// serviceOptions are the boolean filters create understands.
+var serviceOptions = []TaxonomyEntry{
+	{ID: "wheelchair_accessible", Label: "Wheelchair accessible", Param: "accessible"},
+	{ID: "include_closed", Label: "Include permanently closed", Param: "includeClosed"},
+	{ID: "exclude_temporarily_closed", Label: "Hide temporarily closed", Param: "excludeTemporarilyClosed"},
+	{ID: "good_for_kids", Label: "Good for kids", Param: "goodForKids"},
+	{ID: "allows_dogs", Label: "Dogs allowed", Param: "allowsDogs"},
+	{ID: "serves_beer", Label: "Serves beer", Param: "servesBeer"},
+	{ID: "serves_wine", Label: "Serves wine", Param: "servesWine"},
+	{ID: "serves_cocktails", Label: "Serves cocktails", Param: "servesCocktails"},
+	{ID: "exclude_bars_only", Label: "No bars or clubs", Param: "excludeBarsOnly"},
+	{ID: "late_night", Label: "Open late", Param: "lateNight"},
+	{ID: "drive_through", Label: "Drive-through", Param: "driveThrough"},
+}
+
+var sortKeys = []TaxonomyEntry{
+	{ID: sortByScore, Label: "Best rated", Param: "sortBy"},
+	{ID: sortByDistance, Label: "Nearest", Param: "sortBy"},
+}
+
+var priceLevelNames = []string{"Inexpensive", "Moderate", "Expensive", "Very expensive"}
+
+// handleTaxonomy lists the values the API understands so client pickers stay
+// in sync. The version combines the response schema and cuisine taxonomy
+// versions, and the ETag lets clients revalidate cheaply. Price labels use
+// the currency at lat/long when given, and the locale's otherwise.
+func handleTaxonomy(ctx context.Context, req events.APIGatewayProxyRequest, acceptLanguage string, lat, long float64) (events.APIGatewayProxyResponse, error) {
+	locale := localeFor(acceptLanguage)
+	currency := locale.Currency
+	if lat != 0 || long != 0 {
+		currency = currencyAt(ctx, maps.LatLng{Lat: lat, Lng: long}, locale)
+	}
+	cuisineTaxonomy := loadedCuisines()
+	taxonomy := Taxonomy{
+		Version:        fmt.Sprintf("%s.%d", schemaVersion, cuisineTaxonomy.Version),
+		Cuisines:       []TaxonomyEntry{},
+		DietaryTags:    []TaxonomyEntry{},
+		AmbianceTags:   []TaxonomyEntry{},
+		ServiceOptions: serviceOptions,
+		SortKeys:       sortKeys,
+		MealTypes:      []TaxonomyEntry{},
+	}
+	for _, m := range mealTypes {
+		taxonomy.MealTypes = append(taxonomy.MealTypes, TaxonomyEntry{ID: m.ID, Label: m.Label, Param: "mealType"})
+	}
+	for _, def := range cuisineTaxonomy.Cuisines {
+		entry := TaxonomyEntry{ID: def.ID, Label: localizedCuisine(def.ID, locale), Param: "cuisine"}
+		taxonomy.Cuisines = append(taxonomy.Cuisines, entry)
+		if def.Dietary {
+			taxonomy.DietaryTags = append(taxonomy.DietaryTags, entry)
+		}
+	}
+	for _, tag := range ambianceTags {
+		taxonomy.AmbianceTags = append(taxonomy.AmbianceTags, TaxonomyEntry{ID: tag.ID, Label: tag.Label, Param: "ambiance"})
+	}
+	for level, name := range priceLevelNames {
+		taxonomy.PriceLevels = append(taxonomy.PriceLevels, TaxonomyEntry{
+			ID:    fmt.Sprint(level + 1),
+			Label: fmt.Sprintf("%s (%s)", name, strings.Repeat(currency, level+1)),
+			Param: "maxPrice",
+		})
+	}
+	body, err := json.Marshal(taxonomy)
+	if err != nil {
+		return serverError(err)
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	headers := map[string]string{
+		"Content-Type":                  "application/json",
+		"Access-Control-Allow-Origin":   "*",
+		"Access-Control-Expose-Headers": "ETag",
+		"Cache-Control":                 fmt.Sprintf("public, max-age=%d", taxonomyMaxAge),
+		"ETag":                          etag,
+		"Vary":                          "Accept-Language",
+	}
+	if headerValue(req.Headers, "If-None-Match") == etag {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNotModified, Headers: headers}, nil
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode:      http.StatusOK,
+		Headers:         headers,
+		IsBase64Encoded: false,
+		Body:            string(body),
+	}, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+const tenantReloadInterval = time.Minute
+
+type TenantBranding struct {
+	DisplayName  string `dynamodbav:"displayName" json:"displayName,omitempty"`
+	PrimaryColor string `dynamodbav:"primaryColor" json:"primaryColor,omitempty"`
+	LogoURL      string `dynamodbav:"logoUrl" json:"logoUrl,omitempty"`
+}
+
+type tenantProfile struct {
+	ClientID      string          `dynamodbav:"clientId"`
+	DefaultRadius uint            `dynamodbav:"defaultRadius"`
+	Provider      string          `dynamodbav:"provider"`
+	RateLimit     int             `dynamodbav:"rateLimit"`
+	Branding      *TenantBranding `dynamodbav:"branding"`
+}
+
+type cachedTenant struct {
+	profile  *tenantProfile
+	loadedAt time.Time
+}
+
+var tenants = struct {
+	sync.Mutex
+	byClient map[string]cachedTenant
+}{byClient: map[string]cachedTenant{}}
+
+// loadTenant returns the profile for clientID, re-reading it from the table
+// once the cached copy is older than tenantReloadInterval so config edits go
+// live without a redeploy. A missing profile is cached as nil.
+func loadTenant(ctx context.Context, clientID string) (*tenantProfile, error) {
+	if cfg.TenantsTable == "" || clientID == "" {
+		return nil, nil
+	}
+	tenants.Lock()
+	cached, ok := tenants.byClient[clientID]
+	tenants.Unlock()
+	if ok && time.Since(cached.loadedAt) < tenantReloadInterval {
+		return cached.profile, nil
+	}
+	out, err := db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(cfg.TenantsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"clientId": {S: aws.String(clientID)},
+		},
+	})
+	if err != nil {
+		if ok {
+			errorLogger.Println(err)
+			return cached.profile, nil
+		}
+		return nil, err
+	}
+	var profile *tenantProfile
+	if len(out.Item) > 0 {
+		profile = &tenantProfile{}
+		err = dynamodbattribute.UnmarshalMap(out.Item, profile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tenants.Lock()
+	tenants.byClient[clientID] = cachedTenant{profile: profile, loadedAt: time.Now()}
+	tenants.Unlock()
+	return profile, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+	_ "time/tzdata"
+
+	"googlemaps.github.io/maps"
+)
+
+// timezoneCache is keyed by coordinates rounded to ~1km; time zone borders
+// don't move, so entries live for a month.
+var timezoneCache = newTTLCache(30 * 24 * time.Hour)
+
+// locationAt resolves the IANA time zone at a point with the Time Zone API,
+// so opening hours, meal periods and scheduling use the restaurant's local
+// clock rather than the Lambda's UTC one. It returns nil if the zone can't be
+// resolved.
+func locationAt(ctx context.Context, point maps.LatLng) *time.Location {
+	key := fmt.Sprintf("%.2f,%.2f", math.Round(point.Lat*100)/100, math.Round(point.Lng*100)/100)
+	if cached, ok := timezoneCache.get(key); ok {
+		return cached.(*time.Location)
+	}
+	client, err := googleClient()
+	if err != nil {
+		errorLogger.Println(err)
+		return nil
+	}
+	tz, err := client.Timezone(ctx, &maps.TimezoneRequest{Location: &point, Timestamp: time.Now()})
+	if err != nil || tz == nil {
+		errorLogger.Println(err)
+		return nil
+	}
+	loc, err := time.LoadLocation(tz.TimeZoneID)
+	if err != nil {
+		loc = time.FixedZone(tz.TimeZoneName, tz.RawOffset+tz.DstOffset)
+	}
+	timezoneCache.set(key, loc)
+	return loc
+}
+
+// placeTimezone prefers the resolved IANA zone, which knows about DST
+// transitions, and falls back to the utc_offset Google returned with the
+// place.
+func placeTimezone(ctx context.Context, place maps.PlaceDetailsResult) *time.Location {
+	if loc := locationAt(ctx, place.Geometry.Location); loc != nil {
+		return loc
+	}
+	return placeLocation(place)
+}
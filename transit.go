@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const (
+	transitWalkMeters  = 800
+	transitIdealMeters = 150
+	transitConcurrency = 5
+)
+
+var transitCache = newTTLCache(7 * 24 * time.Hour)
+
+type TransitAccess struct {
+	NearestStop       string `json:"nearestStop,omitempty"`
+	NearestStopMeters int    `json:"nearestStopMeters,omitempty"`
+	StopsNearby       int    `json:"stopsNearby"`
+	Score             int    `json:"score"`
+}
+
+func respondTransit(ctx context.Context, location maps.LatLng) (*TransitAccess, error) {
+	var client *maps.Client
+	var err error
+	client, err = googleClient()
+	if err != nil {
+		return nil, err
+	}
+	r := &maps.NearbySearchRequest{
+		Location: &location,
+		Radius:   transitWalkMeters,
+		Type:     maps.PlaceTypeTransitStation,
+	}
+	resp, err := client.NearbySearch(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	access := &TransitAccess{}
+	nearest := math.MaxFloat64
+	for _, stop := range resp.Results {
+		d := distanceMeters(location, stop.Geometry.Location)
+		if d > transitWalkMeters {
+			continue
+		}
+		access.StopsNearby++
+		if d < nearest {
+			nearest = d
+			access.NearestStop = stop.Name
+			access.NearestStopMeters = int(math.Round(d))
+		}
+	}
+	access.Score = transitScore(nearest, access.StopsNearby)
+	return access, nil
+}
+
+// transitScore is 0–100: full marks for a stop within a short walk, falling
+// linearly to zero at transitWalkMeters, with a small bonus for having a
+// choice of stops.
+func transitScore(nearestMeters float64, stops int) int {
+	if stops == 0 {
+		return 0
+	}
+	proximity := 1 - (nearestMeters-transitIdealMeters)/(transitWalkMeters-transitIdealMeters)
+	score := 90*math.Min(math.Max(proximity, 0), 1) + 2*float64(stops-1)
+	return int(math.Round(math.Min(score, 100)))
+}
+
+func enrichTransit(ctx context.Context, biteArray *BiteResponse) {
+	sem := make(chan struct{}, transitConcurrency)
+	var wg sync.WaitGroup
+	for i := range biteArray.Results {
+		wg.Add(1)
+		go func(result *BiteResult) {
+			defer wg.Done()
+			if cached, ok := transitCache.get(result.PlaceID); ok {
+				result.Transit = cached.(*TransitAccess)
+				return
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			access, err := respondTransit(ctx, result.Geometry.Location)
+			if err != nil {
+				errorLogger.Println(err)
+				return
+			}
+			transitCache.set(result.PlaceID, access)
+			result.Transit = access
+		}(&biteArray.Results[i])
+	}
+	wg.Wait()
+}
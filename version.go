@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// buildCommit, buildTime and schemaVersion are set by the Makefile through
+// -ldflags -X. schemaVersion tracks the shape of BiteResponse.
+var (
+	buildCommit   = "dev"
+	buildTime     = "unknown"
+	schemaVersion = "1"
+)
+
+type VersionInfo struct {
+	Commit        string   `json:"commit"`
+	BuildTime     string   `json:"buildTime"`
+	SchemaVersion string   `json:"schemaVersion"`
+	Stage         string   `json:"stage"`
+	Providers     []string `json:"providers"`
+	GoVersion     string   `json:"goVersion"`
+	GOOS          string   `json:"goos"`
+	GOARCH        string   `json:"goarch"`
+	Runtime       string   `json:"runtime,omitempty"`
+	MemoryMB      string   `json:"memoryMB,omitempty"`
+}
+
+// handleVersion reports exactly what is deployed, for support triage and to
+// confirm an arm64 rollout from the client side. It backs both the version
+// and about verbs and the unauthenticated GET /about route.
+func handleVersion() (events.APIGatewayProxyResponse, error) {
+	return jsonSuccess(VersionInfo{
+		Commit:        buildCommit,
+		BuildTime:     buildTime,
+		SchemaVersion: schemaVersion,
+		Stage:         cfg.Stage,
+		Providers:     cfg.Providers,
+		GoVersion:     runtime.Version(),
+		GOOS:          runtime.GOOS,
+		GOARCH:        runtime.GOARCH,
+		Runtime:       os.Getenv("AWS_EXECUTION_ENV"),
+		MemoryMB:      os.Getenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE"),
+	})
+}
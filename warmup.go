@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"googlemaps.github.io/maps"
+)
+
+// warmerEvent matches both an EventBridge scheduled rule ("Scheduled Event"
+// from aws.events) and the {"warmer": true} payload used by warming plugins.
+type warmerEvent struct {
+	Source     string `json:"source"`
+	DetailType string `json:"detail-type"`
+	Warmer     bool   `json:"warmer"`
+}
+
+func (e warmerEvent) isWarmup() bool {
+	return e.Warmer || (e.Source == "aws.events" && e.DetailType == "Scheduled Event")
+}
+
+// warmupCacheKeys is how many of the hottest search cache keys each warmup
+// reloads from L2.
+const warmupCacheKeys = 20
+
+type WarmupResponse struct {
+	Warm       bool   `json:"warm"`
+	InitType   string `json:"initType,omitempty"`
+	Tenants    int    `json:"tenants"`
+	CacheKeys  int    `json:"cacheKeys"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// handleEvent is the Lambda entry point. Warmup pings are answered here
+// without reaching the router, so they never make a billable Places call;
+// everything else is an API Gateway proxy request.
+func handleEvent(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var probe warmerEvent
+	if json.Unmarshal(raw, &probe) == nil && probe.isWarmup() {
+		return warmup(ctx), nil
+	}
+	var req events.APIGatewayProxyRequest
+	err := json.Unmarshal(raw, &req)
+	if err != nil {
+		return nil, err
+	}
+	return logPayloads(router)(ctx, req)
+}
+
+// warmup re-reads SSM overrides, so rotated secrets are picked up by warm
+// containers, refreshes every tenant profile this container has served, and
+// reloads its hottest search cache entries from L2. The provider is never
+// called; an entry L2 doesn't have is left for the next search to fill.
+func warmup(ctx context.Context) WarmupResponse {
+	start := time.Now()
+	refreshSettings()
+	var cacheKeys int
+	if cfg.SearchCacheTTL > 0 {
+		cacheKeys = searchCache().reloadHottest(ctx, warmupCacheKeys)
+	}
+	return WarmupResponse{
+		Warm:       true,
+		InitType:   os.Getenv("AWS_LAMBDA_INITIALIZATION_TYPE"),
+		Tenants:    refreshTenants(ctx),
+		CacheKeys:  cacheKeys,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+}
+
+func refreshSettings() {
+	if os.Getenv("CONFIG_SSM_PATH") == "" {
+		return
+	}
+	fresh, err := loadSettings(os.LookupEnv)
+	if err == nil {
+		err = fresh.validate()
+	}
+	if err != nil {
+		errorLogger.Println(err)
+		return
+	}
+	if fresh.GoogleAPIKey != cfg.GoogleAPIKey {
+		google.client, google.err = maps.NewClient(maps.WithAPIKey(fresh.GoogleAPIKey))
+	}
+	cfg = fresh
+}
+
+func refreshTenants(ctx context.Context) int {
+	tenants.Lock()
+	clientIDs := make([]string, 0, len(tenants.byClient))
+	for clientID, cached := range tenants.byClient {
+		clientIDs = append(clientIDs, clientID)
+		tenants.byClient[clientID] = cachedTenant{profile: cached.profile}
+	}
+	tenants.Unlock()
+	for _, clientID := range clientIDs {
+		_, err := loadTenant(ctx, clientID)
+		if err != nil {
+			errorLogger.Println(err)
+		}
+	}
+	return len(clientIDs)
+}